@@ -0,0 +1,87 @@
+package anyhash
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"unsafe"
+
+	"github.com/hikitani/anyhash/internal"
+)
+
+// Algorithm is a seeded hash function. A value is hashed as a chain of
+// segments, each hashed with the hash of the ones before as its seed, so any
+// algorithm is driven by the same plan.
+type Algorithm interface {
+	Hash(b []byte, seed uint64) uint64
+}
+
+var (
+	// Wyhash is the 64-bit MemhashFallback, the same on every platform. It
+	// is the fastest, but not resistant to chosen inputs.
+	Wyhash Algorithm = wyhash{}
+
+	// FNV1a is the 64-bit FNV-1a, simple and stable, but slow on long
+	// inputs and weak on short ones.
+	FNV1a Algorithm = fnv1a{}
+
+	// XXHash64 is the 64-bit xxHash, fast and stable.
+	XXHash64 Algorithm = xxHash64{}
+
+	// SHA256 takes 64 bits of the SHA-256 digest of the seed and the
+	// segment. It is slow and allocates, but hard to collide on purpose.
+	SHA256 Algorithm = sha256Sum{}
+)
+
+// SipHash24 returns SipHash-2-4 keyed with key. Unlike the other algorithms
+// it resists inputs crafted to collide as long as key is kept secret.
+func SipHash24(key [16]byte) Algorithm {
+	return sipHash24{
+		k0: binary.LittleEndian.Uint64(key[:8]),
+		k1: binary.LittleEndian.Uint64(key[8:]),
+	}
+}
+
+type wyhash struct{}
+
+func (wyhash) Hash(b []byte, seed uint64) uint64 {
+	return internal.Hash64(bytesData(b), seed, uintptr(len(b)))
+}
+
+type fnv1a struct{}
+
+func (fnv1a) Hash(b []byte, seed uint64) uint64 {
+	return internal.FNV1a64(bytesData(b), seed, uintptr(len(b)))
+}
+
+type xxHash64 struct{}
+
+func (xxHash64) Hash(b []byte, seed uint64) uint64 {
+	return internal.XXHash64(bytesData(b), seed, uintptr(len(b)))
+}
+
+type sipHash24 struct {
+	k0, k1 uint64
+}
+
+func (s sipHash24) Hash(b []byte, seed uint64) uint64 {
+	return internal.SipHash24(bytesData(b), s.k0^seed, s.k1, uintptr(len(b)))
+}
+
+type sha256Sum struct{}
+
+func (sha256Sum) Hash(b []byte, seed uint64) uint64 {
+	var s [8]byte
+	binary.LittleEndian.PutUint64(s[:], seed)
+
+	d := sha256.New()
+	d.Write(s[:])
+	d.Write(b)
+	return binary.LittleEndian.Uint64(d.Sum(nil))
+}
+
+func bytesData(b []byte) unsafe.Pointer {
+	if len(b) == 0 {
+		return nil
+	}
+	return unsafe.Pointer(&b[0])
+}
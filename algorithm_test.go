@@ -0,0 +1,119 @@
+package anyhash
+
+import (
+	"testing"
+)
+
+type testAlgorithm struct {
+	name string
+	alg  Algorithm
+}
+
+var testAlgorithms = []testAlgorithm{
+	{"Wyhash", Wyhash},
+	{"FNV1a", FNV1a},
+	{"XXHash64", XXHash64},
+	{"SipHash24", SipHash24([16]byte{1, 2, 3})},
+	{"SHA256", SHA256},
+}
+
+func TestAlgorithms(t *testing.T) {
+	type record struct {
+		id   int
+		name string
+		tags []string `anyhash:"unordered"`
+		m    map[string]int
+		next *record
+	}
+
+	for _, a := range testAlgorithms {
+		t.Run(a.name, func(t *testing.T) {
+			t.Run("Bytes", func(t *testing.T) {
+				h, err := New[[]byte](7, WithAlgorithm(a.alg))
+				if err != nil {
+					t.Fatalf("expected nil err, got %s", err)
+				}
+
+				b := []byte("segment")
+				if got, want := h.GetHash64(b), a.alg.Hash(b, 7); got != want {
+					t.Fatalf("got %#x, want %#x", got, want)
+				}
+			})
+			for _, opts := range [][]Option{{WithAlgorithm(a.alg)}, {WithAlgorithm(a.alg), WithFraming()}} {
+				t.Run("Equal", testEqual(opts,
+					record{id: 1, tags: []string{"a", "b"}, m: map[string]int{"x": 1, "y": 2}},
+					record{id: 1, tags: []string{"b", "a"}, m: map[string]int{"y": 2, "x": 1}},
+				))
+				t.Run("Differ", testDiffer(opts,
+					record{},
+					record{id: 1},
+					record{name: "a"},
+					record{tags: []string{"a"}},
+					record{m: map[string]int{"a": 1}},
+					record{next: &record{}},
+				))
+				t.Run("Differ128", testDiffer128(opts,
+					record{},
+					record{id: 1},
+					record{next: &record{}},
+				))
+			}
+		})
+	}
+
+	t.Run("Distinct", func(t *testing.T) {
+		seen := map[uint64]string{}
+		algs := append([]testAlgorithm{{"SipHash24OtherKey", SipHash24([16]byte{3, 2, 1})}}, testAlgorithms...)
+		for _, a := range algs {
+			h, err := New[string](0, WithAlgorithm(a.alg))
+			if err != nil {
+				t.Fatalf("expected nil err, got %s", err)
+			}
+
+			hv := h.GetHash64("value")
+			if name, ok := seen[hv]; ok {
+				t.Fatalf("%s and %s have the same hash %#x", name, a.name, hv)
+			}
+			seen[hv] = a.name
+		}
+	})
+
+	t.Run("WyhashMatchesPortable", func(t *testing.T) {
+		v := testPortable{I: -1, S: "wyhash", Is: []int{1, 2}, M: map[string]uint{"a": 1}}
+		h, err := New[testPortable](5, WithPortable())
+		if err != nil {
+			t.Fatalf("expected nil err, got %s", err)
+		}
+		hw, err := New[testPortable](5, WithPortable(), WithAlgorithm(Wyhash))
+		if err != nil {
+			t.Fatalf("expected nil err, got %s", err)
+		}
+
+		if got, want := hw.GetHash64(v), h.GetHash64(v); got != want {
+			t.Fatalf("got %#x, want %#x", got, want)
+		}
+	})
+}
+
+func BenchmarkAlgorithms(b *testing.B) {
+	type record struct {
+		id   int64
+		name string
+		vals []float64
+	}
+	v := record{id: 1, name: "benchmark", vals: make([]float64, 32)}
+
+	for _, a := range testAlgorithms {
+		b.Run(a.name, func(b *testing.B) {
+			h, err := New[record](0, WithAlgorithm(a.alg))
+			if err != nil {
+				b.Fatalf("expected nil err, got %s", err)
+			}
+
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				h.GetHash64(v)
+			}
+		})
+	}
+}
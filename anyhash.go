@@ -6,7 +6,7 @@ import (
 	"reflect"
 	"unsafe"
 
-	"github.com/hikitani/anyhash/internal"
+	"github.com/hikitani/anyhash/internal/structtag"
 )
 
 //go:nosplit
@@ -15,29 +15,131 @@ func noescape(p unsafe.Pointer) unsafe.Pointer {
 	return unsafe.Pointer(x ^ 0)
 }
 
+// escapeSink is never set, but the compiler cannot tell.
+var escapeSink struct {
+	on bool
+	v  any
+}
+
+// escape moves what v points to to the heap, as if it were kept. Hashed values
+// are passed through noescape, but the structs, slices and maps they point to
+// must not stay on the stack: the cycle guards keep their addresses off the
+// stack, where the runtime does not update them when the stack moves.
+func escape[T any](v T) {
+	if escapeSink.on {
+		escapeSink.v = v
+	}
+}
+
 type AnyHasher[T any] struct {
-	ptrAndSizeGetters []ptrAndSizeGetter
-	seed              uint
+	plan *plan
+	seed uint64
+	opts options
 }
 
 func (h *AnyHasher[T]) GetHash(v T) uint {
+	return uint(h.GetHash64(v))
+}
+
+// GetHash64 returns the hash of v as 64 bits. Hashers made WithPortable return
+// the same hash for the same value on every platform.
+func (h *AnyHasher[T]) GetHash64(v T) uint64 {
+	escape(v)
 	p := noescape(unsafe.Pointer(&v))
-	var seed = uintptr(h.seed)
-	for _, getter := range h.ptrAndSizeGetters {
-		np, sz := getter.getPtrAndSize(p)
-		seed = internal.MemhashFallback(np, seed, sz)
-	}
+	w := h.writer(false)
+	wp := (*writer)(noescape(unsafe.Pointer(&w)))
+	h.plan.writeTo(wp, p)
+
+	return w.seed
+}
 
-	return uint(seed)
+// Hash128 is a 128-bit hash.
+type Hash128 struct {
+	Hi, Lo uint64
+}
+
+// GetHash128 returns the hash of v as 128 bits on every platform, following
+// the same plan as GetHash.
+func (h *AnyHasher[T]) GetHash128(v T) Hash128 {
+	escape(v)
+	p := noescape(unsafe.Pointer(&v))
+	w := h.writer(true)
+	wp := (*writer)(noescape(unsafe.Pointer(&w)))
+	h.plan.writeTo(wp, p)
+
+	return Hash128{Hi: w.seed2, Lo: w.seed}
+}
+
+// Equal reports whether a and b are equal the way h hashes them: it follows
+// the same fields, pointers and tags and compares floats by the same rules, so
+// that equal values always have equal hashes. Unlike reflect.DeepEqual, NaNs
+// are equal (with WithRawFloats, floats with equal bits are), ignored fields
+// are skipped, and nil and empty slices and maps are equal unless h is made
+// WithFraming.
+func (h *AnyHasher[T]) Equal(a, b T) bool {
+	escape(a)
+	escape(b)
+	pa, pb := noescape(unsafe.Pointer(&a)), noescape(unsafe.Pointer(&b))
+	e := equaler{framed: h.opts.framed}
+	ep := (*equaler)(noescape(unsafe.Pointer(&e)))
+	return h.plan.equal(ep, pa, pb)
+}
+
+func (h *AnyHasher[T]) writer(wide bool) writer {
+	return writer{
+		seed:     h.seed,
+		framed:   h.opts.framed,
+		portable: h.opts.portable,
+		alg:      h.opts.alg,
+		wide:     wide,
+		seed2:    h.seed,
+	}
 }
 
-type hashBuilder[T any] struct {
-	h       *AnyHasher[T]
+type hashBuilder struct {
+	opts    options
 	c       cycleDeclChecker
 	baseTyp reflect.Type
+
+	// pl is the plan being filled, plans are the plans of types reached
+	// through pointers.
+	pl    *plan
+	plans map[reflect.Type]*plan
+	dyn   *dynPlans
+}
+
+func buildPlan(typ reflect.Type, opts options, dyn *dynPlans) (*plan, error) {
+	b := hashBuilder{
+		opts: opts,
+		c: cycleDeclChecker{
+			typEdge:   map[string]map[string]struct{}{},
+			typVisits: map[string]visitStatus{},
+		},
+		baseTyp: typ,
+		plans:   map[reflect.Type]*plan{},
+		dyn:     dyn,
+	}
+	return b.planOf(typ)
+}
+
+func (b *hashBuilder) planOf(typ reflect.Type) (*plan, error) {
+	if pl, ok := b.plans[typ]; ok {
+		return pl, nil
+	}
+
+	pl := &plan{}
+	b.plans[typ] = pl
+
+	parent := b.pl
+	b.pl = pl
+	defer func() { b.pl = parent }()
+	if err := b.fill(reflect.New(typ).Elem(), 0, nil, 0); err != nil {
+		return nil, err
+	}
+	return pl, nil
 }
 
-func (b *hashBuilder[T]) fill(
+func (b *hashBuilder) fill(
 	v reflect.Value,
 	offset uintptr,
 	parentTyp reflect.Type,
@@ -57,64 +159,338 @@ func (b *hashBuilder[T]) fill(
 		}
 	}
 
-	var ptrAndSizeGetter ptrAndSizeGetter
+	if hasHashHook(typ) {
+		b.pl.steps = append(b.pl.steps, &hookGetter{
+			offset:   offset,
+			ptrDepth: ptrDepth,
+			typ:      typ,
+		})
+		return nil
+	}
+
+	var step planStep
 	switch k := typ.Kind(); k {
 	case reflect.String:
-		ptrAndSizeGetter = &stringGetter{
+		step = &stringGetter{
 			offset:   offset,
 			ptrDepth: ptrDepth,
 		}
 	case reflect.Slice:
+		if needsElemPlan(typ.Elem()) {
+			elem, err := b.planOf(typ.Elem())
+			if err != nil {
+				return err
+			}
+			step = &sliceElemsGetter{
+				offset:   offset,
+				ptrDepth: ptrDepth,
+				elemSz:   typ.Elem().Size(),
+				elem:     elem,
+				cyclic:   leadsBack(typ),
+			}
+			break
+		}
 		elemSz, err := getElemSzOfSlice(typ.Elem())
 		if err != nil {
 			return err
 		}
-		ptrAndSizeGetter = &sliceGetter{
-			offset:   offset,
-			ptrDepth: ptrDepth,
-			elemSz:   elemSz,
+		step = &sliceGetter{
+			offset:    offset,
+			ptrDepth:  ptrDepth,
+			elemSz:    elemSz,
+			floats:    b.blockFloats(typ.Elem()),
+			spans:     paddingSpans(typ.Elem()),
+			scalars:   b.blockScalars(typ.Elem()),
+			rawFloats: b.opts.rawFloats,
 		}
 	case reflect.Array:
+		if needsElemPlan(typ.Elem()) {
+			elem, err := b.planOf(typ.Elem())
+			if err != nil {
+				return err
+			}
+			step = &arrayElemsGetter{
+				offset:   offset,
+				ptrDepth: ptrDepth,
+				len:      typ.Len(),
+				elemSz:   typ.Elem().Size(),
+				elem:     elem,
+			}
+			break
+		}
 		len, elemSz, err := getLenAndElemSzArray(typ)
 		if err != nil {
 			return err
 		}
-		ptrAndSizeGetter = &arrayGetter{
-			offset:   offset,
-			ptrDepth: ptrDepth,
-			len:      len,
-			elemSz:   elemSz,
+		step = &arrayGetter{
+			offset:    offset,
+			ptrDepth:  ptrDepth,
+			len:       len,
+			elemSz:    elemSz,
+			floats:    b.blockFloats(innermostElem(typ)),
+			spans:     paddingSpans(innermostElem(typ)),
+			scalars:   b.blockScalars(innermostElem(typ)),
+			rawFloats: b.opts.rawFloats,
 		}
 	case reflect.Struct:
 		for i := 0; i < typ.NumField(); i++ {
 			field := typ.Field(i)
-			if err := b.fill(v.Field(i), field.Offset+offset, typ, ptrDepth); err != nil {
+			tag, err := structtag.Parse(field.Tag.Get("anyhash"))
+			if err != nil {
+				return fmt.Errorf("anyhash: bad tag of field %s in %s: %w", field.Name, typ, err)
+			}
+			if tag.Skip {
+				continue
+			}
+			if err := b.fillField(v.Field(i), field.Offset+offset, typ, ptrDepth, tag); err != nil {
 				return err
 			}
 		}
+	case reflect.Map:
+		key, err := b.planOf(typ.Key())
+		if err != nil {
+			return err
+		}
+		elem, err := b.planOf(typ.Elem())
+		if err != nil {
+			return err
+		}
+		step = &mapGetter{
+			offset:   offset,
+			ptrDepth: ptrDepth,
+			typ:      typ,
+			key:      key,
+			elem:     elem,
+			plainKey: plainKey(typ.Key()),
+			cyclic:   leadsBack(typ),
+		}
 	case reflect.Pointer:
 		if typ.Elem().Kind() == reflect.Struct {
-			return errors.New("pointer of struct cannot be hashed")
+			elem, err := b.planOf(typ.Elem())
+			if err != nil {
+				return err
+			}
+			step = &structPtrGetter{
+				offset:   offset,
+				ptrDepth: ptrDepth + 1,
+				elem:     elem,
+			}
+			break
 		}
 		if err := b.fill(reflect.Indirect(reflect.New(typ.Elem())), offset, parentTyp, ptrDepth+1); err != nil {
 			return err
 		}
-	case reflect.Chan, reflect.Invalid, reflect.Func, reflect.Map,
-		reflect.Interface, reflect.UnsafePointer:
+	case reflect.Interface:
+		step = &interfaceGetter{
+			offset:   offset,
+			ptrDepth: ptrDepth,
+			typ:      typ,
+			dyn:      b.dyn,
+		}
+	case reflect.Chan, reflect.Invalid, reflect.Func, reflect.UnsafePointer:
 		return fmt.Errorf("type %s cannot be hashed", k.String())
+	case reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		if scalars := b.blockScalars(typ); scalars != nil {
+			step = &scalarGetter{
+				offset:    offset,
+				ptrDepth:  ptrDepth,
+				scalars:   scalars,
+				rawFloats: b.opts.rawFloats,
+			}
+			break
+		}
+		if b.opts.rawFloats {
+			step = &baseTypeGetter{
+				offset:   offset,
+				ptrDepth: ptrDepth,
+				elemSz:   typ.Size(),
+			}
+			break
+		}
+		step = &floatGetter{
+			offset:   offset,
+			ptrDepth: ptrDepth,
+			floats:   floatsOf(typ, 0),
+			elemSz:   typ.Size(),
+		}
 	default:
-		ptrAndSizeGetter = &baseTypeGetter{
+		if scalars := b.blockScalars(typ); scalars != nil {
+			step = &scalarGetter{
+				offset:   offset,
+				ptrDepth: ptrDepth,
+				scalars:  scalars,
+			}
+			break
+		}
+		step = &baseTypeGetter{
 			offset:   offset,
 			ptrDepth: ptrDepth,
 			elemSz:   typ.Size(),
 		}
 	}
-	if ptrAndSizeGetter != nil {
-		b.h.ptrAndSizeGetters = append(b.h.ptrAndSizeGetters, ptrAndSizeGetter)
+	if step != nil {
+		b.pl.steps = append(b.pl.steps, step)
 	}
 	return nil
 }
 
+// blockFloats returns the floats to make canonical in the elements of type typ
+// of a flat slice or array.
+func (b *hashBuilder) blockFloats(typ reflect.Type) []floatPos {
+	if b.opts.rawFloats {
+		return nil
+	}
+	return floatsOf(typ, 0)
+}
+
+// blockScalars returns the scalars of typ if a portable hasher cannot hash
+// values of typ as they lie in memory, and nil otherwise.
+func (b *hashBuilder) blockScalars(typ reflect.Type) []scalar {
+	if !b.opts.portable {
+		return nil
+	}
+	if scalars := scalarsOf(typ, 0, nil); needsEncoding(scalars) {
+		return scalars
+	}
+	return nil
+}
+
+// innermostElem returns the element type of nested arrays, the type that flat
+// arrays are hashed as a block of.
+func innermostElem(typ reflect.Type) reflect.Type {
+	for typ.Kind() == reflect.Array {
+		typ = typ.Elem()
+	}
+	return typ
+}
+
+// fillField fills the struct field v, applying the options of its tag.
+func (b *hashBuilder) fillField(
+	v reflect.Value,
+	offset uintptr,
+	parentTyp reflect.Type,
+	ptrDepth int,
+	tag structtag.Tag,
+) error {
+	if !tag.OmitEmpty && !tag.Unordered {
+		return b.fill(v, offset, parentTyp, ptrDepth)
+	}
+
+	var field planStep
+	if tag.Unordered {
+		typ := v.Type()
+		if k := typ.Kind(); k != reflect.Slice && k != reflect.Array {
+			return fmt.Errorf("anyhash: unordered field of %s must be slice or array, got %s", parentTyp, k)
+		}
+		elem, err := b.planOf(typ.Elem())
+		if err != nil {
+			return err
+		}
+		u := &unorderedGetter{
+			offset:   offset,
+			ptrDepth: ptrDepth,
+			isSlice:  typ.Kind() == reflect.Slice,
+			elemSz:   typ.Elem().Size(),
+			elem:     elem,
+		}
+		if u.isSlice {
+			u.cyclic = leadsBack(typ)
+		} else {
+			u.len = typ.Len()
+		}
+		field = u
+	} else {
+		pl := &plan{}
+		parent := b.pl
+		b.pl = pl
+		err := b.fill(v, offset, parentTyp, ptrDepth)
+		b.pl = parent
+		if err != nil {
+			return err
+		}
+		field = pl
+	}
+
+	if tag.OmitEmpty {
+		field = &omitEmptyGetter{
+			offset: offset,
+			typ:    v.Type(),
+			field:  field,
+		}
+	}
+	b.pl.steps = append(b.pl.steps, field)
+	return nil
+}
+
+// needsElemPlan reports whether elements of type elemTyp are hashed one by one
+// with their own plan instead of as one block of memory, that is whether they
+// reach memory outside of themselves, hash through HashAppender or have tagged
+// fields.
+func needsElemPlan(elemTyp reflect.Type) bool {
+	if hasHashHook(elemTyp) {
+		return true
+	}
+
+	switch elemTyp.Kind() {
+	case reflect.Pointer, reflect.String, reflect.Slice,
+		reflect.Map, reflect.Interface:
+		return true
+	case reflect.Array:
+		return needsElemPlan(elemTyp.Elem())
+	case reflect.Struct:
+		if hasTags(elemTyp) {
+			return true
+		}
+		for i := 0; i < elemTyp.NumField(); i++ {
+			if needsElemPlan(elemTyp.Field(i).Type) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// leadsBack reports whether the elements of a slice or map of type typ can hold
+// the slice or map again, that is whether typ is reachable from them or they
+// hold interfaces. Paths through struct pointers are left out: cycles on them
+// are cut at the structs, as cmd/anyhashgen does.
+func leadsBack(typ reflect.Type) bool {
+	seen := make(map[reflect.Type]bool)
+	var reaches func(t reflect.Type) bool
+	reaches = func(t reflect.Type) bool {
+		if t == typ {
+			return true
+		}
+		if seen[t] {
+			return false
+		}
+		seen[t] = true
+
+		switch t.Kind() {
+		case reflect.Interface:
+			return true
+		case reflect.Pointer:
+			return t.Elem().Kind() != reflect.Struct && reaches(t.Elem())
+		case reflect.Slice, reflect.Array:
+			return reaches(t.Elem())
+		case reflect.Map:
+			return reaches(t.Key()) || reaches(t.Elem())
+		case reflect.Struct:
+			for i := 0; i < t.NumField(); i++ {
+				if reaches(t.Field(i).Type) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	if typ.Kind() == reflect.Map && reaches(typ.Key()) {
+		return true
+	}
+	return reaches(typ.Elem())
+}
+
 func elemHasPointers(elemTyp reflect.Type) bool {
 	switch k := elemTyp.Kind(); k {
 	case reflect.Invalid, reflect.Chan, reflect.Func,
@@ -144,7 +520,7 @@ func structHasPointers(structType reflect.Type) bool {
 }
 
 func checkElemTypeOfArrayOrSlice(elemTyp reflect.Type) error {
-	invalidElemErr := errors.New("element of array or slice must be basic type (bool, int, float, complex, not pointer) or struct without pointers")
+	invalidElemErr := errors.New("element of array or slice must not contain chan, func or unsafe pointer")
 
 	if elemHasPointers(elemTyp) {
 		return invalidElemErr
@@ -182,32 +558,34 @@ func getElemSzOfSlice(elemTyp reflect.Type) (int, error) {
 	return int(elemTyp.Size()), nil
 }
 
-func New[T any](seed uint) (*AnyHasher[T], error) {
-	var v T
-	val := reflect.ValueOf(v)
+// New returns a stable hasher: the same seed gives the same hashes in every
+// run, so they can be persisted (see WithPortable). Hashes of a stable hasher
+// are predictable, so inputs colliding on them are easy to find.
+func New[T any](seed uint, opts ...Option) (*AnyHasher[T], error) {
+	return newHasher[T](uint64(seed), opts)
+}
 
-	h := &AnyHasher[T]{
-		ptrAndSizeGetters: []ptrAndSizeGetter{},
-		seed:              seed,
-	}
+// NewPortable returns a stable hasher made WithPortable, seeded with all 64 bits
+// of seed even where uint has 32, so that its hashes can be reproduced on every
+// platform.
+func NewPortable[T any](seed uint64, opts ...Option) (*AnyHasher[T], error) {
+	return newHasher[T](seed, append([]Option{WithPortable()}, opts...))
+}
 
-	c := cycleDeclChecker{
-		typEdge:   map[string]map[string]struct{}{},
-		typVisits: map[string]visitStatus{},
-	}
+func newHasher[T any](seed uint64, opts []Option) (*AnyHasher[T], error) {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
 
-	if val.Kind() == reflect.Invalid {
-		return nil, errors.New("anyhash: got invalid type")
+	h := &AnyHasher[T]{
+		seed: seed,
 	}
-
-	b := hashBuilder[T]{
-		c:       c,
-		h:       h,
-		baseTyp: val.Type(),
+	for _, opt := range opts {
+		opt(&h.opts)
 	}
-	err := b.fill(val, 0, nil, 0)
+
+	pl, err := buildPlan(typ, h.opts, &dynPlans{opts: h.opts})
 	if err != nil {
 		return nil, err
 	}
+	h.plan = pl
 	return h, nil
 }
@@ -1,6 +1,7 @@
 package anyhash
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 	"unsafe"
@@ -108,52 +109,598 @@ func TestStructs(t *testing.T) {
 	}, []byte{164, 98, 1, 2, 255, 255, 10, 11}))
 }
 
+func TestStackGrowth(t *testing.T) {
+	type record struct {
+		id   int
+		name string
+		bs   []byte
+	}
+	v := record{id: 1, name: "stack", bs: []byte{1, 2, 3}}
+
+	h, err := New[record](3, WithFraming())
+	if err != nil {
+		t.Fatalf("expected nil err, got %s", err)
+	}
+	want := h.GetHash(v)
+
+	for depth := 0; depth < 300; depth++ {
+		got := make(chan uint)
+		go func() {
+			got <- hashAtDepth(h, v, depth)
+		}()
+		if got := <-got; got != want {
+			t.Fatalf("depth %d: got %#x, want %#x", depth, got, want)
+		}
+	}
+}
+
+func hashAtDepth[T any](h *AnyHasher[T], v T, depth int) uint {
+	var pad [64]byte
+	if depth == 0 {
+		return h.GetHash(v)
+	}
+	return hashAtDepth(h, v, depth-1) + uint(pad[depth%len(pad)])
+}
+
+type testNode struct {
+	v    int
+	next *testNode
+}
+
+type testTree struct {
+	l, r *testTree
+	v    int16
+}
+
+func TestStructPointers(t *testing.T) {
+	t.Run("Pointer[Struct]", testType(&struct {
+		a int8
+		b int16
+	}{
+		a: -1,
+		b: 25252,
+	}, []byte{255}, []byte{164, 98}))
+	ps := &struct{ a int8 }{a: -1}
+	t.Run("Pointer[Pointer[Struct]]", testType(&ps, []byte{255}))
+
+	for _, opts := range [][]Option{nil, {WithFraming()}} {
+		name := "Unframed"
+		if opts != nil {
+			name = "Framed"
+		}
+		t.Run(name, func(t *testing.T) {
+			t.Run("Struct[Pointer[Struct]]", testDiffer(opts,
+				struct{ s *struct{ a int } }{},
+				struct{ s *struct{ a int } }{&struct{ a int }{}},
+				struct{ s *struct{ a int } }{&struct{ a int }{1}},
+			))
+			t.Run("List", testDiffer(opts,
+				&testNode{v: 1},
+				&testNode{v: 1, next: &testNode{v: 2}},
+				&testNode{v: 1, next: &testNode{v: 2, next: &testNode{}}},
+				newCyclicList(1),
+				newCyclicList(2),
+				newCyclicList(3),
+			))
+			t.Run("Tree", testDiffer(opts,
+				&testTree{v: 1, l: &testTree{v: 2}},
+				&testTree{v: 1, r: &testTree{v: 2}},
+				&testTree{v: 1, l: &testTree{v: 2}, r: &testTree{v: 2}},
+			))
+		})
+	}
+
+	t.Run("EqualValues", func(t *testing.T) {
+		h, err := New[*testTree](0)
+		if err != nil {
+			t.Fatalf("expected nil err, got %s", err)
+		}
+
+		shared := &testTree{v: 2}
+		a := &testTree{v: 1, l: shared, r: shared}
+		b := &testTree{v: 1, l: &testTree{v: 2}, r: &testTree{v: 2}}
+		if got, want := h.GetHash(a), h.GetHash(b); got != want {
+			t.Fatalf("got %d, want %d", got, want)
+		}
+
+		hl, err := New[*testNode](0)
+		if err != nil {
+			t.Fatalf("expected nil err, got %s", err)
+		}
+		if got, want := hl.GetHash(newCyclicList(3)), hl.GetHash(newCyclicList(3)); got != want {
+			t.Fatalf("got %d, want %d", got, want)
+		}
+	})
+
+	t.Run("StackCycle", func(t *testing.T) {
+		h, err := New[*testNode](0)
+		if err != nil {
+			t.Fatalf("expected nil err, got %s", err)
+		}
+		heap := newCyclicList(400)
+		want := h.GetHash64(heap)
+
+		for depth := 0; depth < 100; depth++ {
+			type result struct {
+				hash  uint64
+				equal bool
+			}
+			got := make(chan result)
+			go func() {
+				hash, equal := hashStackCycle(h, heap, depth)
+				got <- result{hash, equal}
+			}()
+			if got := <-got; got.hash != want || !got.equal {
+				t.Fatalf("depth %d: got %#x, equal %t, want %#x, equal true", depth, got.hash, got.equal, want)
+			}
+		}
+	})
+}
+
+// hashStackCycle returns the hash of a cycle of 400 nodes that the compiler may
+// keep on the stack and whether it equals other, after depth frames, so that
+// the stack may grow in the middle of either.
+func hashStackCycle(h *AnyHasher[*testNode], other *testNode, depth int) (uint64, bool) {
+	var pad [64]byte
+	if depth > 0 {
+		hash, equal := hashStackCycle(h, other, depth-1)
+		return hash + uint64(pad[depth%len(pad)]), equal
+	}
+
+	var nodes [400]testNode
+	for i := range nodes {
+		nodes[i].next = &nodes[(i+1)%len(nodes)]
+	}
+	return h.GetHash64(&nodes[0]), h.Equal(&nodes[0], other)
+}
+
+func newCyclicList(n int) *testNode {
+	head := &testNode{}
+	node := head
+	for i := 1; i < n; i++ {
+		node.next = &testNode{}
+		node = node.next
+	}
+	node.next = head
+	return head
+}
+
+type cyclicMap map[string]cyclicMap
+
+type cyclicSlice []cyclicSlice
+
+func newCyclicMap() cyclicMap {
+	m := cyclicMap{}
+	m["x"] = m
+	return m
+}
+
+func newCyclicSlice(n int) cyclicSlice {
+	s := make(cyclicSlice, n)
+	for i := range s {
+		s[i] = s
+	}
+	return s
+}
+
+func TestCyclicContainers(t *testing.T) {
+	for _, opts := range [][]Option{nil, {WithFraming()}} {
+		name := "Unframed"
+		if opts != nil {
+			name = "Framed"
+		}
+		t.Run(name, func(t *testing.T) {
+			t.Run("Map", testDiffer(opts,
+				newCyclicMap(),
+				cyclicMap{"x": newCyclicMap()},
+				cyclicMap{"x": cyclicMap{}},
+				cyclicMap{"y": newCyclicMap()},
+			))
+			t.Run("Slice", testDiffer(opts,
+				newCyclicSlice(1),
+				newCyclicSlice(2),
+				cyclicSlice{cyclicSlice{}},
+			))
+			t.Run("Slice[Any]", func(t *testing.T) {
+				s := []any{1, nil}
+				s[1] = s
+				testDiffer(opts, s, []any{1, []any{}}, []any{1, 1})(t)
+			})
+		})
+	}
+
+	t.Run("EqualValues", func(t *testing.T) {
+		hm, err := New[cyclicMap](0)
+		if err != nil {
+			t.Fatalf("expected nil err, got %s", err)
+		}
+		if got, want := hm.GetHash128(newCyclicMap()), hm.GetHash128(newCyclicMap()); got != want {
+			t.Fatalf("got %#x, want %#x", got, want)
+		}
+
+		hs, err := New[cyclicSlice](0)
+		if err != nil {
+			t.Fatalf("expected nil err, got %s", err)
+		}
+		if got, want := hs.GetHash128(newCyclicSlice(2)), hs.GetHash128(newCyclicSlice(2)); got != want {
+			t.Fatalf("got %#x, want %#x", got, want)
+		}
+	})
+
+	t.Run("Unordered", func(t *testing.T) {
+		type set struct {
+			s []*set `anyhash:"unordered"`
+		}
+		a := &set{}
+		a.s = []*set{a, {}}
+		b := &set{}
+		b.s = []*set{{}, b}
+		testDiffer(nil, a, &set{s: []*set{{}}})(t)
+
+		h, err := New[*set](0)
+		if err != nil {
+			t.Fatalf("expected nil err, got %s", err)
+		}
+		if got, want := h.GetHash64(a), h.GetHash64(b); got != want {
+			t.Fatalf("got %#x, want %#x", got, want)
+		}
+		if !h.Equal(a, b) {
+			t.Fatal("equal sets are not equal")
+		}
+	})
+}
+
+func TestMaps(t *testing.T) {
+	type labels struct {
+		name   string
+		labels map[string]string
+	}
+
+	for _, opts := range [][]Option{nil, {WithFraming()}} {
+		name := "Unframed"
+		if opts != nil {
+			name = "Framed"
+		}
+		t.Run(name, func(t *testing.T) {
+			t.Run("Map[int]int", testDiffer(opts,
+				map[int]int{},
+				map[int]int{1: 2},
+				map[int]int{2: 1},
+				map[int]int{1: 2, 2: 1},
+				map[int]int{1: 1, 2: 2},
+			))
+			t.Run("Map[string]Map[string]int", testDiffer(opts,
+				map[string]map[string]int{"a": {"b": 1}},
+				map[string]map[string]int{"a": {"c": 1}},
+				map[string]map[string]int{"a": {}, "b": {"b": 1}},
+			))
+			t.Run("Struct[Map]", testDiffer(opts,
+				labels{name: "a", labels: map[string]string{"k": "v"}},
+				labels{name: "a", labels: map[string]string{"k": "w"}},
+				labels{name: "b", labels: map[string]string{"k": "v"}},
+			))
+		})
+	}
+	t.Run("NilAndEmptyMap", testFramedDiffer(map[string]int(nil), map[string]int{}))
+
+	t.Run("Order", func(t *testing.T) {
+		testSameHash(t, func(reversed bool) map[string]int {
+			m := map[string]int{}
+			for i := 0; i < 100; i++ {
+				j := i
+				if reversed {
+					j = 99 - i
+				}
+				m[fmt.Sprint(j)] = j
+			}
+			return m
+		})
+		testSameHash(t, func(reversed bool) []map[int][]byte {
+			a, b := map[int][]byte{}, map[int][]byte{}
+			for i := 0; i < 50; i++ {
+				a[i] = []byte{byte(i)}
+				b[49-i] = []byte{byte(49 - i)}
+			}
+			if reversed {
+				a, b = b, a
+			}
+			return []map[int][]byte{a, {1: nil}}
+		})
+		testSameHash(t, func(reversed bool) [2]map[string]*testNode {
+			m := map[string]*testNode{"x": {v: 1}, "y": newCyclicList(2)}
+			if reversed {
+				m = map[string]*testNode{"y": newCyclicList(2), "x": {v: 1}}
+			}
+			return [2]map[string]*testNode{m, nil}
+		})
+	})
+}
+
+// testSameHash checks that the values built by mk hash equally in every mode.
+func testSameHash[T any](t *testing.T, mk func(reversed bool) T) {
+	t.Helper()
+	for _, opts := range [][]Option{nil, {WithFraming()}} {
+		h, err := New[T](0, opts...)
+		if err != nil {
+			t.Fatalf("expected nil err, got %s", err)
+		}
+
+		want := h.GetHash(mk(false))
+		for i := 0; i < 10; i++ {
+			if got := h.GetHash(mk(i%2 == 1)); got != want {
+				t.Fatalf("got %d, want %d", got, want)
+			}
+		}
+	}
+}
+
+type testShape interface {
+	area() float64
+}
+
+type testCircle struct{ r float64 }
+
+func (c testCircle) area() float64 { return 3 * c.r * c.r }
+
+type testSquare struct{ s float64 }
+
+func (s testSquare) area() float64 { return s.s * s.s }
+
+func TestInterfaces(t *testing.T) {
+	var nilAny any
+	for _, opts := range [][]Option{nil, {WithFraming()}} {
+		name := "Unframed"
+		if opts != nil {
+			name = "Framed"
+		}
+		t.Run(name, func(t *testing.T) {
+			t.Run("Any", testDiffer(opts,
+				nil,
+				any(int32(1)),
+				any(uint32(1)),
+				any(int(1)),
+				any("1"),
+				any([]byte("1")),
+				any(&testNode{v: 1}),
+				any(testNode{v: 1}),
+				any(map[string]any{"a": 1}),
+				any([]any{1, "a", nil}),
+			))
+			t.Run("Pointer[Any]", testDiffer(opts, nil, &nilAny))
+			t.Run("Shape", testDiffer[testShape](opts,
+				nil,
+				testCircle{1},
+				testSquare{1},
+				testCircle{2},
+			))
+			t.Run("Struct[Error]", testDiffer(opts,
+				struct{ err error }{},
+				struct{ err error }{errors.New("a")},
+				struct{ err error }{errors.New("b")},
+				struct{ err error }{fmt.Errorf("wrap: %w", errors.New("a"))},
+			))
+		})
+	}
+
+	t.Run("EqualValues", func(t *testing.T) {
+		testSameHash(t, func(reversed bool) []any {
+			return []any{int16(1), "a", &testNode{v: 2}, errors.New("c")}
+		})
+		testSameHash(t, func(reversed bool) struct{ s testShape } {
+			return struct{ s testShape }{testCircle{1}}
+		})
+	})
+
+	t.Run("Unhashable", func(t *testing.T) {
+		h, err := New[any](0)
+		if err != nil {
+			t.Fatalf("expected nil err, got %s", err)
+		}
+
+		defer func() {
+			if recover() == nil {
+				t.Fatal("expected panic")
+			}
+		}()
+		h.GetHash(func() {})
+	})
+}
+
+func TestIndirectElems(t *testing.T) {
+	type named struct {
+		Name string
+	}
+	a, b := 1, 2
+
+	t.Run("Slice[String]", testType([]string{"a", "bc", ""}, []byte("a"), []byte("bc"), nil))
+	t.Run("Slice[Slice[int16]]", testType([][]int16{{-1, -2}, {11111}}, []byte{255, 255, 254, 255}, []byte{103, 43}))
+	t.Run("Array[String]", testType([4]string{"a", "b"}, []byte("a"), []byte("b"), nil, nil))
+	t.Run("Array[Array[String]]", testType([2][2]string{{"a"}, {"b", "c"}}, []byte("a"), nil, []byte("b"), []byte("c")))
+	t.Run("Slice[Struct]", testType([]named{{"a"}, {"b"}}, []byte("a"), []byte("b")))
+	t.Run("Slice[Pointer[int16]]", testType([]*int16{new(int16)}, []byte{0, 0}))
+	t.Run("Slice[Pointer[Struct]]", testType([]*named{{"a"}, {"b"}}, []byte("a"), []byte("b")))
+
+	for _, opts := range [][]Option{nil, {WithFraming()}} {
+		name := "Unframed"
+		if opts != nil {
+			name = "Framed"
+		}
+		t.Run(name, func(t *testing.T) {
+			t.Run("Slice[Pointer[int]]", testDiffer(opts,
+				[]*int{nil, &a},
+				[]*int{&a, nil},
+				[]*int{&a, &b},
+				[]*int{&b, &a},
+			))
+			t.Run("Array[Struct[Slice]]", testDiffer(opts,
+				[2]struct{ s []int16 }{{[]int16{1}}, {[]int16{2}}},
+				[2]struct{ s []int16 }{{[]int16{2}}, {[]int16{1}}},
+			))
+		})
+	}
+	t.Run("Framed", func(t *testing.T) {
+		t.Run("Slice[String]", testFramedDiffer(
+			[]string{"ab", ""},
+			[]string{"a", "b"},
+			[]string{"", "ab"},
+			[]string{"ab"},
+			[]string{},
+			nil,
+		))
+		t.Run("Slice[Slice[byte]]", testFramedDiffer(
+			[][]byte{nil},
+			[][]byte{{}},
+			[][]byte{},
+			[][]byte{{1}, {}},
+			[][]byte{{}, {1}},
+		))
+	})
+}
+
 func TestDisallowedTypes(t *testing.T) {
-	t.Run("Pointer[Struct]", testDisallowedType(&struct{}{}))
-	t.Run("Struct[Pointer[Struct]]", testDisallowedType(struct{ s *struct{} }{}))
 	t.Run("Chan", testDisallowedType(make(chan struct{})))
 	t.Run("Func", testDisallowedType(func() {}))
-	t.Run("Map", testDisallowedType(map[string]struct{}{}))
-	t.Run("Interface", testDisallowedType(getAny(struct{}{})))
 	t.Run("UnsafePointer", testDisallowedType(unsafe.Pointer(&struct{}{})))
 
 	t.Run("Slice[Chan]", testDisallowedType([]chan struct{}{}))
 	t.Run("Slice[Func]", testDisallowedType([]func(){}))
-	t.Run("Slice[Interface]", testDisallowedType([]any{}))
-	t.Run("Slice[Map]", testDisallowedType([]map[string]struct{}{}))
-	t.Run("Slice[Pointer]", testDisallowedType([]*struct{}{}))
 	t.Run("Slice[UnsafePointer]", testDisallowedType([]unsafe.Pointer{}))
-	t.Run("Slice[Slice]", testDisallowedType([][]struct{}{}))
-	t.Run("Slice[String]", testDisallowedType([]string{}))
 	t.Run("Slice[Struct[Chan]]", testDisallowedType([]struct{ ch chan struct{} }{}))
 	t.Run("Slice[Struct[Func]]", testDisallowedType([]struct{ f func() }{}))
-	t.Run("Slice[Struct[Interface]]", testDisallowedType([]struct{ a any }{}))
-	t.Run("Slice[Struct[Map]]", testDisallowedType([]struct{ m map[string]struct{} }{}))
-	t.Run("Slice[Struct[Pointer]]", testDisallowedType([]struct{ p *int }{}))
 	t.Run("Slice[Struct[UnsafePointer]]", testDisallowedType([]struct{ p unsafe.Pointer }{}))
-	t.Run("Slice[Struct[Slice]]", testDisallowedType([]struct{ s []struct{} }{}))
-	t.Run("Slice[Struct[String]]", testDisallowedType([]struct{ s string }{}))
+	t.Run("Slice[Struct[String, Func]]", testDisallowedType([]struct {
+		s string
+		f func()
+	}{}))
+	t.Run("Slice[Slice[Func]]", testDisallowedType([][]func(){}))
 
 	t.Run("Array[Chan]", testDisallowedType([4]chan struct{}{}))
 	t.Run("Array[Func]", testDisallowedType([4]func(){}))
-	t.Run("Array[Interface]", testDisallowedType([4]any{}))
-	t.Run("Array[Map]", testDisallowedType([4]map[string]struct{}{}))
-	t.Run("Array[Pointer]", testDisallowedType([4]*struct{}{}))
 	t.Run("Array[UnsafePointer]", testDisallowedType([4]unsafe.Pointer{}))
-	t.Run("Array[Slice]", testDisallowedType([4][]struct{}{}))
-	t.Run("Array[String]", testDisallowedType([4]string{}))
 	t.Run("Array[Struct[Chan]]", testDisallowedType([4]struct{ ch chan struct{} }{}))
 	t.Run("Array[Struct[Func]]", testDisallowedType([4]struct{ f func() }{}))
-	t.Run("Array[Struct[Interface]]", testDisallowedType([4]struct{ a any }{}))
-	t.Run("Array[Struct[Map]]", testDisallowedType([4]struct{ m map[string]struct{} }{}))
-	t.Run("Array[Struct[Pointer]]", testDisallowedType([4]struct{ p *int }{}))
 	t.Run("Array[Struct[UnsafePointer]]", testDisallowedType([4]struct{ p unsafe.Pointer }{}))
-	t.Run("Array[Struct[Slice]]", testDisallowedType([4]struct{ s []struct{} }{}))
-	t.Run("Array[Struct[String]]", testDisallowedType([4]struct{ s string }{}))
 }
 
-func getAny(v any) any {
-	return v
+func TestFraming(t *testing.T) {
+	type twoStrings struct {
+		a, b string
+	}
+	type twoSlices struct {
+		a, b []byte
+	}
+
+	t.Run("Strings", testFramedDiffer(
+		twoStrings{"ab", ""},
+		twoStrings{"a", "b"},
+		twoStrings{"", "ab"},
+		twoStrings{"", ""},
+	))
+	t.Run("Slices", testFramedDiffer(
+		twoSlices{[]byte("ab"), nil},
+		twoSlices{[]byte("a"), []byte("b")},
+		twoSlices{nil, []byte("ab")},
+		twoSlices{[]byte("ab"), []byte{}},
+		twoSlices{[]byte{}, []byte("ab")},
+	))
+	t.Run("NilAndEmptySlice", testFramedDiffer([]int16(nil), []int16{}))
+	t.Run("EmptyElems", testFramedDiffer(
+		[]struct{}{},
+		[]struct{}{{}},
+		[]struct{}{{}, {}},
+	))
+	t.Run("NestedStrings", testFramedDiffer(
+		struct {
+			a string
+			b struct{ c, d string }
+		}{"a", struct{ c, d string }{"b", ""}},
+		struct {
+			a string
+			b struct{ c, d string }
+		}{"ab", struct{ c, d string }{"", ""}},
+	))
+
+	t.Run("EqualValues", func(t *testing.T) {
+		h, err := New[twoSlices](0, WithFraming())
+		if err != nil {
+			t.Fatalf("expected nil err, got %s", err)
+		}
+		a := twoSlices{[]byte("ab"), []byte("c")}
+		b := twoSlices{[]byte("abc")[:2], append([]byte{}, 'c')}
+		if got, want := h.GetHash(a), h.GetHash(b); got != want {
+			t.Fatalf("got %d, want %d", got, want)
+		}
+	})
+}
+
+func testFramedDiffer[T any](vs ...T) func(t *testing.T) {
+	return testDiffer([]Option{WithFraming()}, vs...)
+}
+
+func TestNilPointers(t *testing.T) {
+	zeroInt := 0
+	pZeroInt := &zeroInt
+	var nilInt *int
+	emptyStr := ""
+	emptyBytes := []byte{}
+	zeroArr := [2]int16{}
+	type ptrs struct {
+		a *int
+		b *string
+	}
+
+	for _, opts := range [][]Option{nil, {WithFraming()}} {
+		name := "Unframed"
+		if opts != nil {
+			name = "Framed"
+		}
+		t.Run(name, func(t *testing.T) {
+			t.Run("Pointer[int]", testDiffer(opts, nil, &zeroInt))
+			t.Run("Pointer[string]", testDiffer(opts, nil, &emptyStr))
+			t.Run("Pointer[Slice[byte]]", testDiffer(opts, nil, &emptyBytes))
+			t.Run("Pointer[Array[int16]]", testDiffer(opts, nil, &zeroArr))
+			t.Run("Pointer[Pointer[int]]", testDiffer(opts, nil, &nilInt, &pZeroInt))
+			t.Run("Pointer[Pointer[Slice[byte]]]", testDiffer(opts, nil, new(*[]byte)))
+			t.Run("Struct", testDiffer(opts,
+				ptrs{nil, &emptyStr},
+				ptrs{&zeroInt, nil},
+				ptrs{nil, nil},
+				ptrs{&zeroInt, &emptyStr},
+			))
+		})
+	}
+
+	t.Run("Stable", func(t *testing.T) {
+		h, err := New[ptrs](0)
+		if err != nil {
+			t.Fatalf("expected nil err, got %s", err)
+		}
+		otherInt := 0
+		if got, want := h.GetHash(ptrs{a: &zeroInt}), h.GetHash(ptrs{a: &otherInt}); got != want {
+			t.Fatalf("got %d, want %d", got, want)
+		}
+		if got, want := h.GetHash(ptrs{}), h.GetHash(ptrs{}); got != want {
+			t.Fatalf("got %d, want %d", got, want)
+		}
+	})
+}
+
+func testDiffer[T any](opts []Option, vs ...T) func(t *testing.T) {
+	return func(t *testing.T) {
+		h, err := New[T](0, opts...)
+		if err != nil {
+			t.Fatalf("expected nil err, got %s", err)
+		}
+
+		seen := map[uint]int{}
+		for i, v := range vs {
+			hv := h.GetHash(v)
+			if j, ok := seen[hv]; ok {
+				t.Fatalf("values %d and %d have the same hash %d", j, i, hv)
+			}
+			seen[hv] = i
+		}
+	}
 }
 
 func testType[T any](v T, inBytes ...[]byte) func(t *testing.T) {
@@ -0,0 +1,21 @@
+// Package anyhashtest helps tests of code that persists hashes of anyhash.
+package anyhashtest
+
+import (
+	"testing"
+
+	"github.com/hikitani/anyhash"
+)
+
+// CheckFingerprint reports an error if the fingerprint of h is not want, so
+// that changes of T which break persisted hashes fail the tests instead of
+// changing the hashes silently. The error has the new fingerprint and the
+// layout it is the hash of.
+func CheckFingerprint[T any](t testing.TB, h *anyhash.AnyHasher[T], want string) {
+	t.Helper()
+	if got := h.Fingerprint(); got != want {
+		t.Errorf("fingerprint of %T hasher changed: got %s, want %s\n"+
+			"update the persisted hashes and the fingerprint if the change is intended, layout:\n%s",
+			h, got, want, h.Layout())
+	}
+}
@@ -0,0 +1,53 @@
+package anyhashtest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hikitani/anyhash"
+)
+
+type recordingTB struct {
+	testing.TB
+	errors []string
+}
+
+func (tb *recordingTB) Helper() {}
+
+func (tb *recordingTB) Errorf(format string, args ...any) {
+	tb.errors = append(tb.errors, fmt.Sprintf(format, args...))
+}
+
+type user struct {
+	ID   uint64
+	Name string
+}
+
+func TestCheckFingerprint(t *testing.T) {
+	h, err := anyhash.New[user](0, anyhash.WithPortable())
+	if err != nil {
+		t.Fatalf("expected nil err, got %s", err)
+	}
+
+	t.Run("Same", func(t *testing.T) {
+		tb := &recordingTB{TB: t}
+		CheckFingerprint(tb, h, h.Fingerprint())
+		if len(tb.errors) != 0 {
+			t.Fatalf("got errors %q, want none", tb.errors)
+		}
+	})
+
+	t.Run("Changed", func(t *testing.T) {
+		tb := &recordingTB{TB: t}
+		CheckFingerprint(tb, h, "0123456789abcdef0123456789abcdef")
+		if len(tb.errors) != 1 {
+			t.Fatalf("got %d errors, want 1", len(tb.errors))
+		}
+		for _, s := range []string{h.Fingerprint(), "\t.Name string"} {
+			if !strings.Contains(tb.errors[0], s) {
+				t.Fatalf("error %q does not contain %q", tb.errors[0], s)
+			}
+		}
+	})
+}
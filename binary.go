@@ -0,0 +1,60 @@
+package anyhash
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// The binary forms of the structures built on a hasher start with a magic of
+// 4 bytes, the fingerprint of the hasher and the check of its seed, followed
+// by the fields of the structure, all little endian.
+const binaryHeaderSize = 4 + 16 + 8
+
+// seedProbe is hashed by a hasher to tell its seed apart without storing it.
+const seedProbe = "anyhash seed probe"
+
+// seedCheck returns the hash of a fixed input with h, which is the same for
+// hashers of the same fingerprint and seed.
+func (h *AnyHasher[T]) seedCheck() uint64 {
+	b := []byte(seedProbe)
+	w := h.writer(false)
+	w.write(bytesData(b), uintptr(len(b)))
+	return w.seed
+}
+
+// appendHeader appends the header of a binary form starting with magic to b.
+func (h *AnyHasher[T]) appendHeader(b []byte, magic string) []byte {
+	fp := h.fingerprint()
+	b = append(b, magic...)
+	b = append(b, fp[:]...)
+	return appendUint64(b, h.seedCheck())
+}
+
+// readHeader checks that data is the binary form of a structure named what,
+// starting with magic and made with a hasher that hashes like h, and returns
+// its fields, which must take at least size bytes.
+func (h *AnyHasher[T]) readHeader(data []byte, magic, what string, size int) ([]byte, error) {
+	if len(data) < binaryHeaderSize+size || string(data[:len(magic)]) != magic {
+		return nil, fmt.Errorf("anyhash: invalid %s data", what)
+	}
+	fp := h.fingerprint()
+	if string(data[len(magic):len(magic)+len(fp)]) != string(fp[:]) {
+		return nil, fmt.Errorf("anyhash: %s of a hasher with a different fingerprint", what)
+	}
+	if binary.LittleEndian.Uint64(data[len(magic)+len(fp):]) != h.seedCheck() {
+		return nil, fmt.Errorf("anyhash: %s of a hasher with a different seed", what)
+	}
+	return data[binaryHeaderSize:], nil
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+func appendUint64(b []byte, v uint64) []byte {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], v)
+	return append(b, buf[:]...)
+}
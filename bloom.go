@@ -0,0 +1,166 @@
+package anyhash
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/bits"
+)
+
+// BloomFilter is a set of values of any type hashable by AnyHasher that
+// answers whether it contains a value with false positives but without false
+// negatives. The k bits of a value are derived from its 128-bit hash by
+// double hashing, so a value is hashed once per call. A BloomFilter is not
+// safe for concurrent use.
+type BloomFilter[T any] struct {
+	h    *AnyHasher[T]
+	bits []uint64
+	k    int
+}
+
+// maxBloomHashes bounds the number of bits of a value. More hashes only slow a
+// filter down, and binary forms asking for more are invalid.
+const maxBloomHashes = 64
+
+// NewBloomFilter returns an empty filter hashing values with h that holds n
+// values with a false positive rate of fpr, which must be in (0, 1).
+func NewBloomFilter[T any](h *AnyHasher[T], n int, fpr float64) *BloomFilter[T] {
+	if !(fpr > 0 && fpr < 1) {
+		panic("anyhash: bloom filter false positive rate must be in (0, 1)")
+	}
+	if n < 1 {
+		n = 1
+	}
+
+	// The optimal number of bits is -n ln(fpr) / ln(2)^2, and the optimal
+	// number of hashes is the number of bits per value times ln(2).
+	m := math.Ceil(-float64(n) * math.Log(fpr) / (math.Ln2 * math.Ln2))
+	words := int(math.Ceil(m / 64))
+	k := int(math.Round(float64(words*64) / float64(n) * math.Ln2))
+	if k < 1 {
+		k = 1
+	} else if k > maxBloomHashes {
+		k = maxBloomHashes
+	}
+	return &BloomFilter[T]{h: h, bits: make([]uint64, words), k: k}
+}
+
+// Add adds v to the filter.
+func (f *BloomFilter[T]) Add(v T) {
+	hash := f.h.GetHash128(v)
+	m := uint64(len(f.bits)) * 64
+	for i := 0; i < f.k; i++ {
+		bit := bloomIndex(hash, i, m)
+		f.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+// Contains reports whether v may have been added to the filter. It is false
+// for values that were not added with the false positive rate of the filter,
+// and true for values that were.
+func (f *BloomFilter[T]) Contains(v T) bool {
+	hash := f.h.GetHash128(v)
+	m := uint64(len(f.bits)) * 64
+	for i := 0; i < f.k; i++ {
+		bit := bloomIndex(hash, i, m)
+		if f.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// K returns the number of bits set by every value.
+func (f *BloomFilter[T]) K() int {
+	return f.k
+}
+
+// Bits returns the size of the filter in bits.
+func (f *BloomFilter[T]) Bits() int {
+	return len(f.bits) * 64
+}
+
+// Union adds the values of other to f, which must have been made with the
+// same size and a compatible hasher, as told by Fingerprint.
+func (f *BloomFilter[T]) Union(other *BloomFilter[T]) error {
+	if err := f.compatible(other); err != nil {
+		return err
+	}
+	for i, w := range other.bits {
+		f.bits[i] |= w
+	}
+	return nil
+}
+
+// Intersect leaves in f only the values of other, which must have been made
+// with the same size and a compatible hasher. The false positive rate of the
+// result is at most the one of either filter, but may be higher than the one
+// of a filter built from the common values.
+func (f *BloomFilter[T]) Intersect(other *BloomFilter[T]) error {
+	if err := f.compatible(other); err != nil {
+		return err
+	}
+	for i, w := range other.bits {
+		f.bits[i] &= w
+	}
+	return nil
+}
+
+func (f *BloomFilter[T]) compatible(other *BloomFilter[T]) error {
+	if len(f.bits) != len(other.bits) || f.k != other.k {
+		return errors.New("anyhash: bloom filters of different sizes")
+	}
+	if !f.h.hashesLike(other.h) {
+		return errors.New("anyhash: bloom filters of different hashers")
+	}
+	return nil
+}
+
+// bloomMagic starts the binary form of a BloomFilter, whose fields are the
+// number of hashes and of words and the words of bits.
+const bloomMagic = "AHB1"
+
+// MarshalBinary returns the binary form of f, which UnmarshalBloomFilter
+// reads back.
+func (f *BloomFilter[T]) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 0, binaryHeaderSize+8+8*len(f.bits))
+	b = f.h.appendHeader(b, bloomMagic)
+	b = appendUint32(b, uint32(f.k))
+	b = appendUint32(b, uint32(len(f.bits)))
+	for _, w := range f.bits {
+		b = appendUint64(b, w)
+	}
+	return b, nil
+}
+
+// UnmarshalBloomFilter returns the filter in the binary form data, made by
+// MarshalBinary of a filter with a hasher compatible with h, as told by
+// Fingerprint.
+func UnmarshalBloomFilter[T any](h *AnyHasher[T], data []byte) (*BloomFilter[T], error) {
+	data, err := h.readHeader(data, bloomMagic, "bloom filter", 8)
+	if err != nil {
+		return nil, err
+	}
+
+	k := binary.LittleEndian.Uint32(data)
+	words := binary.LittleEndian.Uint32(data[4:])
+	data = data[8:]
+	if k < 1 || k > maxBloomHashes || words < 1 || uint64(len(data)) != 8*uint64(words) {
+		return nil, errors.New("anyhash: invalid bloom filter data")
+	}
+
+	f := &BloomFilter[T]{h: h, bits: make([]uint64, words), k: int(k)}
+	for i := range f.bits {
+		f.bits[i] = binary.LittleEndian.Uint64(data[8*i:])
+	}
+	return f, nil
+}
+
+// bloomIndex returns the i-th bit of hash in a filter of m bits by enhanced
+// double hashing, mapped to [0, m) by multiplication rather than modulo.
+func bloomIndex(hash Hash128, i int, m uint64) uint64 {
+	n := uint64(i)
+	g := hash.Lo + n*hash.Hi + (n*n*n-n)/6
+	hi, _ := bits.Mul64(g, m)
+	return hi
+}
@@ -0,0 +1,205 @@
+package anyhash
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// testFalsePositives returns the rate of the keys reported by f.
+func testFalsePositives(f *BloomFilter[testKey], keys []testKey) float64 {
+	positives := 0
+	for _, k := range keys {
+		if f.Contains(k) {
+			positives++
+		}
+	}
+	return float64(positives) / float64(len(keys))
+}
+
+func TestBloomFilter(t *testing.T) {
+	n := 20000
+	if testing.Short() {
+		n = 5000
+	}
+	added, absent := testKeys(0, n), testKeys(n, 11*n)
+	h := newTestHasher[testKey](t, WithPortable())
+
+	t.Run("FalsePositiveRate", func(t *testing.T) {
+		for name, opts := range testHasherOptions {
+			h := newTestHasher[testKey](t, opts...)
+			for _, fpr := range []float64{0.1, 0.01, 0.001} {
+				f := NewBloomFilter(h, n, fpr)
+				for _, k := range added {
+					f.Add(k)
+				}
+				for _, k := range added {
+					if !f.Contains(k) {
+						t.Fatalf("%s: false negative %v", name, k)
+					}
+				}
+
+				// The rate of 10n absent values must be near the target.
+				got := testFalsePositives(f, absent)
+				if got > 1.3*fpr+0.0005 {
+					t.Fatalf("%s: target %g: got false positive rate %g", name, fpr, got)
+				}
+			}
+		}
+	})
+
+	t.Run("Size", func(t *testing.T) {
+		f := NewBloomFilter(h, 1000, 0.01)
+		// 9.59 bits and 7 hashes per value are optimal for 1%.
+		if f.Bits() < 9585 || f.Bits() > 9585+64 {
+			t.Fatalf("got %d bits, want 9585 rounded to words", f.Bits())
+		}
+		if f.K() != 7 {
+			t.Fatalf("got %d hashes, want 7", f.K())
+		}
+	})
+
+	t.Run("Union", func(t *testing.T) {
+		a, b := NewBloomFilter(h, n, 0.01), NewBloomFilter(h, n, 0.01)
+		for i, k := range added {
+			if i%2 == 0 {
+				a.Add(k)
+			} else {
+				b.Add(k)
+			}
+		}
+
+		if err := a.Union(b); err != nil {
+			t.Fatalf("expected nil err, got %s", err)
+		}
+		for _, k := range added {
+			if !a.Contains(k) {
+				t.Fatalf("false negative %v", k)
+			}
+		}
+		if got := testFalsePositives(a, absent); got > 0.0135 {
+			t.Fatalf("got false positive rate %g", got)
+		}
+	})
+
+	t.Run("Intersect", func(t *testing.T) {
+		a, b := NewBloomFilter(h, n, 0.01), NewBloomFilter(h, n, 0.01)
+		for i, k := range added {
+			if i%3 != 0 {
+				a.Add(k)
+			}
+			if i%3 != 1 {
+				b.Add(k)
+			}
+		}
+
+		if err := a.Intersect(b); err != nil {
+			t.Fatalf("expected nil err, got %s", err)
+		}
+		for i, k := range added {
+			if i%3 == 2 && !a.Contains(k) {
+				t.Fatalf("false negative %v", k)
+			}
+		}
+		if got := testFalsePositives(a, absent); got > 0.0135 {
+			t.Fatalf("got false positive rate %g", got)
+		}
+	})
+
+	t.Run("Incompatible", func(t *testing.T) {
+		a := NewBloomFilter(h, n, 0.01)
+		if err := a.Union(NewBloomFilter(h, n, 0.001)); err == nil {
+			t.Fatal("expected err for different sizes")
+		}
+
+		seeded, err := New[testKey](1, WithPortable())
+		if err != nil {
+			t.Fatalf("expected nil err, got %s", err)
+		}
+		if err := a.Intersect(NewBloomFilter(seeded, n, 0.01)); err == nil {
+			t.Fatal("expected err for different seeds")
+		}
+
+		// Algorithms differ by their keys as well.
+		var keyed [2]*BloomFilter[testKey]
+		for i := range keyed {
+			h := newTestHasher[testKey](t, WithPortable(), WithAlgorithm(SipHash24([16]byte{byte(i)})))
+			keyed[i] = NewBloomFilter(h, n, 0.01)
+		}
+		if err := keyed[0].Union(keyed[1]); err == nil {
+			t.Fatal("expected err for different keys")
+		}
+
+		// An equal hasher is fine.
+		if err := a.Union(NewBloomFilter(newTestHasher[testKey](t, WithPortable()), n, 0.01)); err != nil {
+			t.Fatalf("expected nil err, got %s", err)
+		}
+	})
+
+	t.Run("Binary", func(t *testing.T) {
+		f := NewBloomFilter(h, n, 0.01)
+		for _, k := range added {
+			f.Add(k)
+		}
+		data, err := f.MarshalBinary()
+		if err != nil {
+			t.Fatalf("expected nil err, got %s", err)
+		}
+
+		g, err := UnmarshalBloomFilter(h, data)
+		if err != nil {
+			t.Fatalf("expected nil err, got %s", err)
+		}
+		if g.K() != f.K() || g.Bits() != f.Bits() {
+			t.Fatalf("got %d bits and %d hashes, want %d and %d", g.Bits(), g.K(), f.Bits(), f.K())
+		}
+		for _, k := range added {
+			if !g.Contains(k) {
+				t.Fatalf("false negative %v", k)
+			}
+		}
+		for _, k := range absent[:1000] {
+			if g.Contains(k) != f.Contains(k) {
+				t.Fatalf("%v: filters disagree", k)
+			}
+		}
+
+		badHashes := append([]byte{}, data...)
+		binary.LittleEndian.PutUint32(badHashes[binaryHeaderSize:], maxBloomHashes+1)
+		testUnmarshalErrors(t, data, UnmarshalBloomFilter[testKey], map[string]testBinaryCase{
+			"Hashes": {badHashes, "invalid"},
+		})
+
+		tiny := NewBloomFilter(h, 1, 1e-30)
+		if tiny.K() != maxBloomHashes {
+			t.Fatalf("got %d hashes, want %d", tiny.K(), maxBloomHashes)
+		}
+		data, err = tiny.MarshalBinary()
+		if err != nil {
+			t.Fatalf("expected nil err, got %s", err)
+		}
+		if _, err := UnmarshalBloomFilter(h, data); err != nil {
+			t.Fatalf("expected nil err, got %s", err)
+		}
+	})
+}
+
+func BenchmarkBloomFilter(b *testing.B) {
+	h := newTestHasher[testKey](b)
+	keys := testKeys(0, 1024)
+	f := NewBloomFilter(h, len(keys), 0.01)
+	for _, k := range keys {
+		f.Add(k)
+	}
+
+	b.Run("Add", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			f.Add(keys[i%len(keys)])
+		}
+	})
+
+	b.Run("Contains", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			f.Contains(keys[i%len(keys)])
+		}
+	})
+}
@@ -0,0 +1,668 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/hikitani/anyhash/internal/structtag"
+)
+
+const (
+	anyhashPath = "github.com/hikitani/anyhash"
+	marker      = "//anyhash:generate"
+)
+
+// generate type checks the package in dir as built with the build tags tags,
+// leaving out the generated file output, and returns the source of the hash
+// functions of its marked types.
+func generate(dir, output string, tags []string) ([]byte, error) {
+	ctxt := build.Default
+	ctxt.BuildTags = append(append([]string(nil), ctxt.BuildTags...), tags...)
+	bp, err := ctxt.ImportDir(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	names := append(append([]string(nil), bp.GoFiles...), bp.CgoFiles...)
+	sort.Strings(names)
+
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, name := range names {
+		if name == output {
+			continue
+		}
+		f, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no Go files in %s besides %s", dir, output)
+	}
+
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	pkg, err := conf.Check(files[0].Name.Name, fset, files, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &generator{pkg: pkg, imports: map[string]string{anyhashPath: "anyhash"}}
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			gd, ok := decl.(*ast.GenDecl)
+			if !ok || gd.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gd.Specs {
+				ts := spec.(*ast.TypeSpec)
+				doc := ts.Doc
+				if doc == nil && len(gd.Specs) == 1 {
+					doc = gd.Doc
+				}
+				if !marked(doc) {
+					continue
+				}
+				if ts.TypeParams != nil {
+					return nil, fmt.Errorf("%s: generic types are not supported", ts.Name.Name)
+				}
+				if err := g.hashFunc(pkg.Scope().Lookup(ts.Name.Name).(*types.TypeName)); err != nil {
+					return nil, fmt.Errorf("%s: %w", ts.Name.Name, err)
+				}
+			}
+		}
+	}
+	if g.funcs.Len() == 0 {
+		return nil, fmt.Errorf("no types marked with %s in %s", marker, dir)
+	}
+
+	for i := 0; i < len(g.structs); i++ {
+		if err := g.structFunc(g.structs[i]); err != nil {
+			return nil, fmt.Errorf("%s: %w", g.typeString(g.structs[i].typ), err)
+		}
+	}
+	return g.source()
+}
+
+func marked(doc *ast.CommentGroup) bool {
+	if doc == nil {
+		return false
+	}
+	for _, c := range doc.List {
+		if strings.TrimSpace(c.Text) == marker {
+			return true
+		}
+	}
+	return false
+}
+
+// generator writes the hash functions of the marked types and the functions
+// writing the struct types they reach.
+type generator struct {
+	pkg     *types.Package
+	imports map[string]string
+
+	funcs   bytes.Buffer
+	structs []structType
+	anon    int
+
+	// b is the body of the function being written, names counts the local
+	// names in it.
+	b     *bytes.Buffer
+	names int
+
+	// nested holds the types being written that are not structs. Only struct
+	// types are written by functions of their own, so only they can recurse.
+	nested []types.Type
+}
+
+type structType struct {
+	typ  types.Type
+	name string
+}
+
+func (g *generator) source() ([]byte, error) {
+	var src bytes.Buffer
+	fmt.Fprintf(&src, "// Code generated by anyhashgen. DO NOT EDIT.\n\npackage %s\n\nimport (\n", g.pkg.Name())
+	paths := make([]string, 0, len(g.imports))
+	for path := range g.imports {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		fmt.Fprintf(&src, "\t%q\n", path)
+	}
+	fmt.Fprintf(&src, ")\n\n")
+	src.Write(g.funcs.Bytes())
+
+	out, err := format.Source(src.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %w", err)
+	}
+	return out, nil
+}
+
+func (g *generator) hashFunc(obj *types.TypeName) error {
+	name := "Hash" + obj.Name()
+	if !obj.Exported() {
+		name = "hash" + strings.ToUpper(obj.Name()[:1]) + obj.Name()[1:]
+	}
+
+	g.begin()
+	if err := g.value("(*v)", obj.Type()); err != nil {
+		return err
+	}
+	fmt.Fprintf(&g.funcs, "// %s writes *v to w, hashing it like AnyHasher[%s] made WithPortable.\n", name, obj.Name())
+	fmt.Fprintf(&g.funcs, "func %s(w *anyhash.Writer, v *%s) {\n%s}\n\n", name, obj.Name(), g.b)
+	return nil
+}
+
+func (g *generator) structFunc(s structType) error {
+	g.begin()
+	if hasHook(s.typ) {
+		g.hook("(*p)")
+	} else if err := g.fields("p", s.typ.Underlying().(*types.Struct)); err != nil {
+		return err
+	}
+	fmt.Fprintf(&g.funcs, "func %s(w *anyhash.Writer, p *%s) {\n%s}\n\n", s.name, g.typeString(s.typ), g.b)
+	return nil
+}
+
+func (g *generator) begin() {
+	g.b = &bytes.Buffer{}
+	g.names = 0
+}
+
+func (g *generator) p(format string, args ...any) {
+	fmt.Fprintf(g.b, format, args...)
+}
+
+// name returns a new local name starting with prefix.
+func (g *generator) name(prefix string) string {
+	g.names++
+	return fmt.Sprintf("%s%d", prefix, g.names)
+}
+
+func (g *generator) typeString(t types.Type) string {
+	return types.TypeString(t, func(p *types.Package) string {
+		if p == g.pkg {
+			return ""
+		}
+		g.imports[p.Path()] = p.Name()
+		return p.Name()
+	})
+}
+
+// structName returns the name of the function writing the fields of the
+// struct type t, adding it if it is new.
+func (g *generator) structName(t types.Type) (string, error) {
+	for _, s := range g.structs {
+		if types.Identical(s.typ, t) {
+			return s.name, nil
+		}
+	}
+
+	var name string
+	if n, ok := t.(*types.Named); ok {
+		if n.TypeArgs().Len() > 0 {
+			return "", fmt.Errorf("generic type %s is not supported", g.typeString(t))
+		}
+		name = "anyhashWrite" + strings.ToUpper(n.Obj().Name()[:1]) + n.Obj().Name()[1:]
+		if pkg := n.Obj().Pkg(); pkg != g.pkg {
+			name = "anyhashWrite" + strings.ToUpper(pkg.Name()[:1]) + pkg.Name()[1:] + n.Obj().Name()
+		}
+	} else {
+		g.anon++
+		name = fmt.Sprintf("anyhashWriteStruct%d", g.anon)
+	}
+	g.structs = append(g.structs, structType{t, name})
+	return name, nil
+}
+
+// value writes the code writing the value x of type t, following the steps
+// of the plan AnyHasher builds for t.
+func (g *generator) value(x string, t types.Type) error {
+	for _, n := range g.nested {
+		if types.Identical(n, t) {
+			return fmt.Errorf("recursive type %s is not supported", g.typeString(t))
+		}
+	}
+	g.nested = append(g.nested, t)
+	defer func() { g.nested = g.nested[:len(g.nested)-1] }()
+
+	// Pointers are followed down to a struct, which is entered to find
+	// cycles, or to the value they point to.
+	depth, ptrTyp := 0, t
+	for {
+		ptr, ok := ptrTyp.Underlying().(*types.Pointer)
+		if !ok {
+			break
+		}
+		depth++
+		elem := ptr.Elem()
+		if _, ok := elem.Underlying().(*types.Struct); ok {
+			return g.deref(x, depth, func(p string) error {
+				if _, named := ptrTyp.(*types.Named); named {
+					p = fmt.Sprintf("(*%s)(%s)", g.typeString(elem), p)
+				}
+				name, err := g.structName(elem)
+				if err != nil {
+					return err
+				}
+				g.p("if w.Enter(%s) {\n%s(w, %s)\nw.Leave(%s)\n}\n", p, name, p, p)
+				return nil
+			})
+		}
+		ptrTyp = elem
+	}
+	if depth == 0 {
+		return g.base(x, t)
+	}
+	return g.deref(x, depth, func(p string) error {
+		return g.base("(*"+p+")", ptrTyp)
+	})
+}
+
+// deref writes the code following depth pointers from x and calling inner
+// with the last one unless one of them is nil.
+func (g *generator) deref(x string, depth int, inner func(p string) error) error {
+	p := g.name("p")
+	g.p("if %s := %s; %s == nil {\nw.WriteNil(0)\n", p, x, p)
+	for i := 1; i < depth; i++ {
+		next := g.name("p")
+		g.p("} else if %s := *%s; %s == nil {\nw.WriteNil(%d)\n", next, p, next, i)
+		p = next
+	}
+	g.p("} else {\nw.WriteFrame(%d)\n", depth)
+	if err := inner(p); err != nil {
+		return err
+	}
+	g.p("}\n")
+	return nil
+}
+
+// base writes the value x of the type t that is not a pointer.
+func (g *generator) base(x string, t types.Type) error {
+	if hasHook(t) {
+		if _, ok := t.Underlying().(*types.Struct); !ok {
+			g.hook(x)
+			return nil
+		}
+	}
+
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		return g.basic(x, u)
+	case *types.Struct:
+		name, err := g.structName(t)
+		if err != nil {
+			return err
+		}
+		g.p("%s(w, %s)\n", name, addr(x))
+	case *types.Slice:
+		g.frame(x)
+		if !needsElemPlan(u.Elem()) {
+			return g.block(x, u.Elem(), true)
+		}
+		i := g.name("i")
+		g.p("for %s := range %s {\n", i, x)
+		if err := g.value(x+"["+i+"]", u.Elem()); err != nil {
+			return err
+		}
+		g.p("}\n")
+	case *types.Array:
+		if !needsElemPlan(u.Elem()) {
+			return g.block(x, t, false)
+		}
+		i := g.name("i")
+		g.p("for %s := range %s {\n", i, x)
+		if err := g.value(x+"["+i+"]", u.Elem()); err != nil {
+			return err
+		}
+		g.p("}\n")
+	case *types.Map:
+		g.frame(x)
+		sum, k, v, sub := g.name("sum"), g.name("k"), g.name("v"), g.name("sub")
+		g.p("var %s uint64\n", sum)
+		g.p("for %s, %s := range %s {\n%s, %s := %s, %s\n", k, v, x, k, v, k, v)
+		g.p("%s := w.Sub()\n{\nw := &%s\n", sub, sub)
+		if err := g.value(k, u.Key()); err != nil {
+			return err
+		}
+		if err := g.value(v, u.Elem()); err != nil {
+			return err
+		}
+		g.p("}\n%s = w.AddSub(%s, &%s)\n}\nw.WriteSum(%s)\n", sum, sum, sub, sum)
+	case *types.Interface:
+		return fmt.Errorf("interface type %s is not supported", g.typeString(t))
+	default:
+		return fmt.Errorf("type %s cannot be hashed", g.typeString(t))
+	}
+	return nil
+}
+
+func (g *generator) basic(x string, b *types.Basic) error {
+	switch b.Kind() {
+	case types.Bool:
+		g.p("w.WriteBool(bool(%s))\n", x)
+	case types.String:
+		g.p("w.WriteString(string(%s))\n", x)
+	case types.Float32:
+		g.p("w.WriteFloat32(float32(%s))\n", x)
+	case types.Float64:
+		g.p("w.WriteFloat64(float64(%s))\n", x)
+	case types.Complex64:
+		g.p("w.WriteComplex64(complex64(%s))\n", x)
+	case types.Complex128:
+		g.p("w.WriteComplex128(complex128(%s))\n", x)
+	default:
+		size := intSize(b)
+		if size == 0 {
+			return fmt.Errorf("type %s cannot be hashed", b)
+		}
+		g.p("w.WriteUint(uint64(%s), %d)\n", x, size)
+	}
+	return nil
+}
+
+// intSize returns the number of bytes portable hashers write for the integer
+// type b, 0 if b is not an integer.
+func intSize(b *types.Basic) int {
+	switch b.Kind() {
+	case types.Int8, types.Uint8:
+		return 1
+	case types.Int16, types.Uint16:
+		return 2
+	case types.Int32, types.Uint32:
+		return 4
+	case types.Int, types.Uint, types.Uintptr, types.Int64, types.Uint64:
+		return 8
+	}
+	return 0
+}
+
+// frame writes the frame of the slice or map x.
+func (g *generator) frame(x string) {
+	g.p("if %s == nil {\nw.WriteFrame(0)\n} else {\nw.WriteFrame(uint64(len(%s)) + 1)\n}\n", x, x)
+}
+
+// block writes the elements of type t of the flat slice x, or the flat array
+// x of type t, as one segment.
+func (g *generator) block(x string, t types.Type, isSlice bool) error {
+	buf, b := g.name("buf"), g.name("b")
+	g.p("{\nvar %s [64]byte\n%s := %s[:0]\n", buf, b, buf)
+	if isSlice {
+		i := g.name("i")
+		g.p("for %s := range %s {\n", i, x)
+		x += "[" + i + "]"
+	}
+	if err := g.appendScalars(b, x, t); err != nil {
+		return err
+	}
+	if isSlice {
+		g.p("}\n")
+	}
+	g.p("w.WriteBytes(%s)\n}\n", b)
+	return nil
+}
+
+// appendScalars appends the scalars of the flat value x of type t to b.
+func (g *generator) appendScalars(b, x string, t types.Type) error {
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		switch u.Kind() {
+		case types.Bool:
+			g.p("%s = w.AppendBool(%s, bool(%s))\n", b, b, x)
+		case types.Float32:
+			g.p("%s = w.AppendFloat32(%s, float32(%s))\n", b, b, x)
+		case types.Float64:
+			g.p("%s = w.AppendFloat64(%s, float64(%s))\n", b, b, x)
+		case types.Complex64:
+			g.p("%s = w.AppendFloat32(%s, real(complex64(%s)))\n", b, b, x)
+			g.p("%s = w.AppendFloat32(%s, imag(complex64(%s)))\n", b, b, x)
+		case types.Complex128:
+			g.p("%s = w.AppendFloat64(%s, real(complex128(%s)))\n", b, b, x)
+			g.p("%s = w.AppendFloat64(%s, imag(complex128(%s)))\n", b, b, x)
+		default:
+			size := intSize(u)
+			if size == 0 {
+				return fmt.Errorf("element of array or slice must not contain %s", u)
+			}
+			g.p("%s = w.AppendUint(%s, uint64(%s), %d)\n", b, b, x, size)
+		}
+	case *types.Array:
+		i := g.name("i")
+		g.p("for %s := range %s {\n", i, x)
+		if err := g.appendScalars(b, x+"["+i+"]", u.Elem()); err != nil {
+			return err
+		}
+		g.p("}\n")
+	case *types.Struct:
+		for i := 0; i < u.NumFields(); i++ {
+			f, err := g.field(u, i)
+			if err != nil {
+				return err
+			}
+			if err := g.appendScalars(b, x+"."+f.Name(), f.Type()); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("element of array or slice must not contain %s", g.typeString(t))
+	}
+	return nil
+}
+
+// field returns the i-th field of s if the generated code can read it.
+func (g *generator) field(s *types.Struct, i int) (*types.Var, error) {
+	f := s.Field(i)
+	if f.Name() == "_" {
+		return nil, errors.New("blank fields are not supported")
+	}
+	if !f.Exported() && f.Pkg() != g.pkg {
+		return nil, fmt.Errorf("unexported field %s of package %s is not supported", f.Name(), f.Pkg().Path())
+	}
+	return f, nil
+}
+
+// hook writes the value x hashing through HashAppender.
+func (g *generator) hook(x string) {
+	recv := addr(x)
+	if strings.HasPrefix(recv, "&") {
+		recv = "(" + recv + ")"
+	}
+	buf, b := g.name("buf"), g.name("b")
+	g.p("{\nvar %s [64]byte\n%s := %s.AppendHash(%s[:0])\n", buf, b, recv, buf)
+	g.p("w.WriteFrame(uint64(len(%s)) + 1)\nw.WriteBytes(%s)\n}\n", b, b)
+}
+
+// fields writes the fields of the struct *p, applying their tags.
+func (g *generator) fields(p string, s *types.Struct) error {
+	for i := 0; i < s.NumFields(); i++ {
+		tag, err := structtag.Parse(reflect.StructTag(s.Tag(i)).Get("anyhash"))
+		if err != nil {
+			return fmt.Errorf("bad tag of field %s: %w", s.Field(i).Name(), err)
+		}
+		if tag.Skip {
+			continue
+		}
+		f, err := g.field(s, i)
+		if err != nil {
+			return err
+		}
+
+		x := p + "." + f.Name()
+		if tag.OmitEmpty {
+			zero, err := g.zero(x, f.Type())
+			if err != nil {
+				return err
+			}
+			g.p("if %s {\nw.WriteFrame(0)\n} else {\nw.WriteFrame(1)\n", zero)
+		}
+		if tag.Unordered {
+			err = g.unordered(x, f.Type())
+		} else {
+			err = g.value(x, f.Type())
+		}
+		if err != nil {
+			return err
+		}
+		if tag.OmitEmpty {
+			g.p("}\n")
+		}
+	}
+	return nil
+}
+
+// unordered writes the elements of the slice or array x of type t, each on
+// its own, as the sum of their hashes.
+func (g *generator) unordered(x string, t types.Type) error {
+	var elem types.Type
+	switch u := t.Underlying().(type) {
+	case *types.Slice:
+		g.frame(x)
+		elem = u.Elem()
+	case *types.Array:
+		elem = u.Elem()
+	default:
+		return fmt.Errorf("unordered field must be slice or array, got %s", g.typeString(t))
+	}
+
+	sum, i, sub := g.name("sum"), g.name("i"), g.name("sub")
+	g.p("var %s uint64\nfor %s := range %s {\n", sum, i, x)
+	g.p("%s := w.Sub()\n{\nw := &%s\n", sub, sub)
+	if err := g.value(x+"["+i+"]", elem); err != nil {
+		return err
+	}
+	g.p("}\n%s = w.AddSub(%s, &%s)\n}\nw.WriteSum(%s)\n", sum, sum, sub, sum)
+	return nil
+}
+
+// zero returns an expression reporting whether x of type t is zero, as
+// reflect.Value.IsZero does.
+func (g *generator) zero(x string, t types.Type) (string, error) {
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		switch {
+		case u.Kind() == types.Bool:
+			return "!" + x, nil
+		case u.Kind() == types.String:
+			return x + ` == ""`, nil
+		case u.Kind() == types.UnsafePointer:
+			return x + " == nil", nil
+		}
+		return x + " == 0", nil
+	case *types.Pointer, *types.Slice, *types.Map, *types.Chan, *types.Signature, *types.Interface:
+		return x + " == nil", nil
+	}
+
+	if types.Comparable(t) {
+		return fmt.Sprintf("%s == (%s{})", x, g.typeString(t)), nil
+	}
+
+	switch u := t.Underlying().(type) {
+	case *types.Struct:
+		var conds []string
+		for i := 0; i < u.NumFields(); i++ {
+			if u.Field(i).Name() == "_" {
+				continue
+			}
+			f, err := g.field(u, i)
+			if err != nil {
+				return "", err
+			}
+			cond, err := g.zero(x+"."+f.Name(), f.Type())
+			if err != nil {
+				return "", err
+			}
+			conds = append(conds, "("+cond+")")
+		}
+		if len(conds) == 0 {
+			return "true", nil
+		}
+		return strings.Join(conds, " && "), nil
+	case *types.Array:
+		i := g.name("i")
+		cond, err := g.zero(x+"["+i+"]", u.Elem())
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("func() bool {\nfor %s := range %s {\nif !(%s) {\nreturn false\n}\n}\nreturn true\n}()", i, x, cond), nil
+	}
+	return "", fmt.Errorf("type %s cannot be hashed", g.typeString(t))
+}
+
+// addr returns the address of the addressable expression x.
+func addr(x string) string {
+	if strings.HasPrefix(x, "(*") && strings.HasSuffix(x, ")") && !strings.ContainsAny(x[2:len(x)-1], "()[]. ") {
+		return x[2 : len(x)-1]
+	}
+	return "&" + x
+}
+
+// hasHook reports whether values of t hash through anyhash.HashAppender,
+// promoted methods included.
+func hasHook(t types.Type) bool {
+	switch t.Underlying().(type) {
+	case *types.Interface, *types.Pointer:
+		return false
+	}
+
+	obj, _, _ := types.LookupFieldOrMethod(types.NewPointer(t), true, nil, "AppendHash")
+	fn, ok := obj.(*types.Func)
+	if !ok {
+		return false
+	}
+	sig := fn.Type().(*types.Signature)
+	return sig.Params().Len() == 1 && sig.Results().Len() == 1 && !sig.Variadic() &&
+		isBytes(sig.Params().At(0).Type()) && isBytes(sig.Results().At(0).Type())
+}
+
+func isBytes(t types.Type) bool {
+	s, ok := t.(*types.Slice)
+	if !ok {
+		return false
+	}
+	b, ok := s.Elem().(*types.Basic)
+	return ok && b.Kind() == types.Byte
+}
+
+// needsElemPlan reports whether elements of type t are written one by one
+// instead of as one block, as anyhash decides it.
+func needsElemPlan(t types.Type) bool {
+	if hasHook(t) {
+		return true
+	}
+
+	switch u := t.Underlying().(type) {
+	case *types.Pointer, *types.Slice, *types.Map, *types.Interface:
+		return true
+	case *types.Basic:
+		return u.Kind() == types.String
+	case *types.Array:
+		return needsElemPlan(u.Elem())
+	case *types.Struct:
+		for i := 0; i < u.NumFields(); i++ {
+			if _, ok := reflect.StructTag(u.Tag(i)).Lookup("anyhash"); ok {
+				return true
+			}
+			if needsElemPlan(u.Field(i).Type()) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerateUpToDate(t *testing.T) {
+	dir := filepath.Join("..", "..", "internal", "gentest")
+	got, err := generate(dir, "anyhash_gen.go", nil)
+	if err != nil {
+		t.Fatalf("expected nil err, got %s", err)
+	}
+	want, err := os.ReadFile(filepath.Join(dir, "anyhash_gen.go"))
+	if err != nil {
+		t.Fatalf("expected nil err, got %s", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatal("internal/gentest/anyhash_gen.go is out of date, run go generate ./...")
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	tests := []struct {
+		name, src, err string
+	}{
+		{"Unmarked", "type T struct{ A int }", "no types marked"},
+		{"Interface", "//anyhash:generate\ntype T struct{ A any }", "interface type any is not supported"},
+		{"Func", "//anyhash:generate\ntype T struct{ F func() }", "cannot be hashed"},
+		{"FlatChan", "//anyhash:generate\ntype T [2]chan int", "element of array or slice must not contain"},
+		{"Blank", "//anyhash:generate\ntype T struct{ _ int }", "blank fields are not supported"},
+		{"Foreign", "import \"strings\"\n\n//anyhash:generate\ntype T struct{ R strings.Reader }", "unexported field"},
+		{"Recursive", "//anyhash:generate\ntype T []T", "recursive type"},
+		{"Generic", "//anyhash:generate\ntype T[E any] struct{ E E }", "generic types"},
+		{"BadTag", "//anyhash:generate\ntype T struct{ A int `anyhash:\"sorted\"` }", "unknown option"},
+		{"Unordered", "//anyhash:generate\ntype T struct{ A int `anyhash:\"unordered\"` }", "must be slice or array"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			src := "package p\n\n" + tt.src + "\n"
+			if err := os.WriteFile(filepath.Join(dir, "p.go"), []byte(src), 0o644); err != nil {
+				t.Fatalf("expected nil err, got %s", err)
+			}
+
+			_, err := generate(dir, "anyhash_gen.go", nil)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("got err %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestGenerateBuildTags(t *testing.T) {
+	dir := t.TempDir()
+	for name, src := range map[string]string{
+		"p.go":       "package p\n\n//anyhash:generate\ntype T struct{ A int }\n",
+		"ignored.go": "//go:build ignore\n\npackage p\n\ntype T struct{ B string }\n",
+		"extra.go":   "//go:build extra\n\npackage p\n\n//anyhash:generate\ntype U struct{ C string }\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+			t.Fatalf("expected nil err, got %s", err)
+		}
+	}
+
+	src, err := generate(dir, "anyhash_gen.go", nil)
+	if err != nil {
+		t.Fatalf("expected nil err, got %s", err)
+	}
+	if !bytes.Contains(src, []byte("func HashT(")) || bytes.Contains(src, []byte("func HashU(")) {
+		t.Fatalf("got source for the wrong files:\n%s", src)
+	}
+
+	src, err = generate(dir, "anyhash_gen.go", []string{"extra"})
+	if err != nil {
+		t.Fatalf("expected nil err, got %s", err)
+	}
+	if !bytes.Contains(src, []byte("func HashU(")) {
+		t.Fatalf("got no source for the tagged file:\n%s", src)
+	}
+}
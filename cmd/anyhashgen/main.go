@@ -0,0 +1,66 @@
+// Command anyhashgen generates hash functions for the types of a package
+// marked with an //anyhash:generate comment:
+//
+//	//go:generate go run github.com/hikitani/anyhash/cmd/anyhashgen
+//
+//	//anyhash:generate
+//	type Foo struct { ... }
+//
+// For each marked type Foo it writes
+//
+//	func HashFoo(w *anyhash.Writer, v *Foo)
+//
+// (hashFoo for unexported types) to anyhash_gen.go. The generated code uses
+// neither reflection nor unsafe, and for w := anyhash.NewWriter(seed, opts...)
+// w.Sum64() equals NewPortable[Foo](seed, opts...).GetHash64(*v). It only
+// matches portable hashers: the hashes of hashers made without WithPortable
+// differ on 32-bit and big endian platforms.
+//
+// The package is read as built for the current GOOS and GOARCH with the build
+// tags given by -tags, so files excluded by their build constraints are left
+// out. Types declared differently under other constraints need a generated
+// file for each of them.
+//
+// Types with interface fields are not supported, nor are blank fields and
+// unexported fields of types of other packages.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	output := flag.String("output", "anyhash_gen.go", "name of the generated file")
+	tags := flag.String("tags", "", "comma-separated list of build tags to apply")
+	flag.Usage = func() {
+		out := flag.CommandLine.Output()
+		fmt.Fprintf(out, "usage: anyhashgen [-output file] [-tags list] [dir]\n\n")
+		fmt.Fprintf(out, "The generated code hashes like hashers made with anyhash.NewPortable only.\n")
+		fmt.Fprintf(out, "Files are selected by their build constraints for GOOS, GOARCH and -tags.\n\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	dir := "."
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+
+	var buildTags []string
+	if *tags != "" {
+		buildTags = strings.Split(*tags, ",")
+	}
+	src, err := generate(dir, *output, buildTags)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "anyhashgen:", err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(filepath.Join(dir, *output), src, 0o644); err != nil {
+		fmt.Fprintln(os.Stderr, "anyhashgen:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,181 @@
+package anyhash
+
+import (
+	"encoding/binary"
+	"math"
+	"unsafe"
+)
+
+// Writer hashes values segment by segment for the code generated by
+// cmd/anyhashgen. Generated code writing a value of T to NewWriter(seed, opts...)
+// gives the hash NewPortable[T](seed, opts...) returns for it, without
+// reflection or unsafe in the generated code.
+type Writer struct {
+	w         writer
+	rawFloats bool
+
+	// visiting holds the structs on the current pointer path with their
+	// depth, keyed by their pointers.
+	visiting map[any]int
+}
+
+// NewWriter returns a writer starting at seed. Writers are always portable.
+func NewWriter(seed uint64, opts ...Option) Writer {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return Writer{
+		w: writer{
+			seed:     seed,
+			framed:   o.framed,
+			portable: true,
+			alg:      o.alg,
+		},
+		rawFloats: o.rawFloats,
+	}
+}
+
+// Sum64 returns the hash of everything written.
+func (w *Writer) Sum64() uint64 {
+	return w.w.seed
+}
+
+// WriteBytes writes b as one segment.
+func (w *Writer) WriteBytes(b []byte) {
+	w.w.write(noescape(bytesData(b)), uintptr(len(b)))
+}
+
+// WriteString writes the frame and the bytes of s.
+func (w *Writer) WriteString(s string) {
+	w.w.writeFrame(uint64(len(s)) + 1)
+	w.w.writeString(s)
+}
+
+// WriteUint writes the size low bytes of v as one segment. Integers are
+// written with their size, int, uint and uintptr with 8.
+func (w *Writer) WriteUint(v uint64, size int) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], v)
+	w.w.write(noescape(unsafe.Pointer(&b)), uintptr(size))
+}
+
+func (w *Writer) WriteBool(v bool) {
+	w.WriteUint(boolByte(v), 1)
+}
+
+func (w *Writer) WriteFloat64(v float64) {
+	w.WriteUint(w.float64Bits(v), 8)
+}
+
+func (w *Writer) WriteFloat32(v float32) {
+	w.WriteUint(uint64(w.float32Bits(v)), 4)
+}
+
+func (w *Writer) WriteComplex128(v complex128) {
+	var b [16]byte
+	binary.LittleEndian.PutUint64(b[:8], w.float64Bits(real(v)))
+	binary.LittleEndian.PutUint64(b[8:], w.float64Bits(imag(v)))
+	w.w.write(noescape(unsafe.Pointer(&b)), 16)
+}
+
+func (w *Writer) WriteComplex64(v complex64) {
+	var b [8]byte
+	binary.LittleEndian.PutUint32(b[:4], w.float32Bits(real(v)))
+	binary.LittleEndian.PutUint32(b[4:], w.float32Bits(imag(v)))
+	w.w.write(noescape(unsafe.Pointer(&b)), 8)
+}
+
+// AppendUint appends the size low bytes of v to b, which is written with
+// WriteBytes. The elements of flat slices and arrays are appended to one block.
+func (w *Writer) AppendUint(b []byte, v uint64, size int) []byte {
+	return appendLittleEndian(b, v, size)
+}
+
+func (w *Writer) AppendBool(b []byte, v bool) []byte {
+	return append(b, byte(boolByte(v)))
+}
+
+func (w *Writer) AppendFloat64(b []byte, v float64) []byte {
+	return appendLittleEndian(b, w.float64Bits(v), 8)
+}
+
+func (w *Writer) AppendFloat32(b []byte, v float32) []byte {
+	return appendLittleEndian(b, uint64(w.float32Bits(v)), 4)
+}
+
+// WriteFrame writes the frame of a slice, map, pointer or field if the writer
+// is framed.
+func (w *Writer) WriteFrame(frame uint64) {
+	w.w.writeFrame(frame)
+}
+
+// WriteNil marks a nil pointer met after level dereferences.
+func (w *Writer) WriteNil(level int) {
+	w.w.writeNil(level)
+}
+
+// Enter reports whether the struct p points to should be written. A struct
+// that is already on the pointer path is written as the distance back to it.
+func (w *Writer) Enter(p any) bool {
+	if w.visiting == nil {
+		w.visiting = map[any]int{}
+	}
+
+	depth := len(w.visiting)
+	if d, ok := w.visiting[p]; ok {
+		w.w.writeMark(uint64(depth-d), cycleSeed)
+		return false
+	}
+
+	w.visiting[p] = depth
+	w.w.writeFrame(0)
+	return true
+}
+
+func (w *Writer) Leave(p any) {
+	delete(w.visiting, p)
+}
+
+// Sub returns a writer hashing an entry of a map or an element of an
+// unordered field on its own, starting at the current seed.
+func (w *Writer) Sub() Writer {
+	return Writer{
+		w:         w.w.sub(),
+		rawFloats: w.rawFloats,
+		visiting:  w.visiting,
+	}
+}
+
+// AddSub returns sum plus the hash of s, a writer returned by Sub. The sum of
+// all entries is written with WriteSum.
+func (w *Writer) AddSub(sum uint64, s *Writer) uint64 {
+	w.visiting = s.visiting
+	return sum + s.w.seed
+}
+
+func (w *Writer) WriteSum(sum uint64) {
+	w.w.writeUint64(sum)
+}
+
+func (w *Writer) float64Bits(v float64) uint64 {
+	if w.rawFloats {
+		return math.Float64bits(v)
+	}
+	return canonical64(math.Float64bits(v))
+}
+
+func (w *Writer) float32Bits(v float32) uint32 {
+	if w.rawFloats {
+		return math.Float32bits(v)
+	}
+	return canonical32(math.Float32bits(v))
+}
+
+func boolByte(v bool) uint64 {
+	if v {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,47 @@
+package anyhash
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// dynPlans caches the plans of dynamic types met in interface values.
+type dynPlans struct {
+	opts  options
+	plans sync.Map
+}
+
+type dynPlan struct {
+	typ   reflect.Type
+	ident string
+	pl    *plan
+}
+
+// planOf returns the plan of the dynamic type typ. Like hashing an unhashable
+// map key, hashing a value of a type that cannot be hashed panics.
+func (d *dynPlans) planOf(typ reflect.Type) *dynPlan {
+	if dp, ok := d.plans.Load(typ); ok {
+		return dp.(*dynPlan)
+	}
+
+	pl, err := buildPlan(typ, d.opts, d)
+	if err != nil {
+		panic(fmt.Sprintf("anyhash: hash of unhashable type %s: %s", typ, err))
+	}
+	dp, _ := d.plans.LoadOrStore(typ, &dynPlan{
+		typ:   typ,
+		ident: typeIdent(typ),
+		pl:    pl,
+	})
+	return dp.(*dynPlan)
+}
+
+// typeIdent names typ by its package path, so that equally named types of
+// different packages are told apart.
+func typeIdent(typ reflect.Type) string {
+	if typ.Name() != "" && typ.PkgPath() != "" {
+		return typ.PkgPath() + "." + typ.Name()
+	}
+	return typ.String()
+}
@@ -0,0 +1,113 @@
+package anyhash
+
+import (
+	"bytes"
+	"reflect"
+	"unsafe"
+)
+
+// equaler compares two values step by step along the plan that hashes them,
+// so values it finds equal always hash equally.
+type equaler struct {
+	framed bool
+
+	// visiting holds the pairs of structs, slices and maps on the current
+	// pointer paths of both values, as visits of the writer. The paths are
+	// short, so it is a stack rather than a map.
+	visiting []visitPair
+}
+
+type visitPair struct {
+	a, b unsafe.Pointer
+	pl   *plan
+	n    int
+}
+
+// enter reports whether the structs at a and b should be compared with pl, or
+// the n elements of the slices at a and b, or the maps at a and b if pl is
+// nil. If either is already on its pointer path, the result is whether both
+// are, at the same depth, and done is set.
+func (e *equaler) enter(a, b unsafe.Pointer, pl *plan, n int) (equal, done bool) {
+	for i := len(e.visiting) - 1; i >= 0; i-- {
+		v := e.visiting[i]
+		if v.pl == pl && v.n == n && (v.a == a || v.b == b) {
+			return v.a == a && v.b == b, true
+		}
+	}
+
+	e.visiting = append(e.visiting, visitPair{a, b, pl, n})
+	return false, false
+}
+
+func (e *equaler) leave() {
+	e.visiting = e.visiting[:len(e.visiting)-1]
+}
+
+// derefEqual resolves the fields at offset of a and b through ptrDepth
+// pointers. Unless done, both are non-nil and still to be compared; otherwise
+// equal tells whether both met a nil after the same number of pointers.
+func derefEqual(a, b unsafe.Pointer, offset uintptr, ptrDepth int) (na, nb unsafe.Pointer, equal, done bool) {
+	na, la := indirect(unsafe.Add(a, offset), ptrDepth)
+	nb, lb := indirect(unsafe.Add(b, offset), ptrDepth)
+	if na == nil || nb == nil {
+		return nil, nil, na == nil && nb == nil && la == lb, true
+	}
+	return na, nb, false, false
+}
+
+// sliceFramesEqual reports whether two slices are written with equal frames:
+// framed, nil and empty slices differ.
+func (e *equaler) sliceFramesEqual(a, b *reflect.SliceHeader) bool {
+	if a.Len != b.Len {
+		return false
+	}
+	return !e.framed || (a.Data == 0) == (b.Data == 0)
+}
+
+// blockEqual reports whether the n elements at a and b are written as equal
+// bytes, following canonicalBlock and compactBlock.
+func blockEqual(a, b unsafe.Pointer, n int, elemSz uintptr, floats []floatPos, spans []span) bool {
+	if n == 0 {
+		return true
+	}
+	if len(floats) != 0 {
+		a = canonicalBlock(a, n, elemSz, floats)
+		b = canonicalBlock(b, n, elemSz, floats)
+	}
+	if spans == nil {
+		sz := uintptr(n) * elemSz
+		return bytes.Equal(unsafe.Slice((*byte)(a), sz), unsafe.Slice((*byte)(b), sz))
+	}
+
+	for i := 0; i < n; i++ {
+		elem := uintptr(i) * elemSz
+		for _, s := range spans {
+			ea := unsafe.Slice((*byte)(unsafe.Add(a, elem+s.offset)), s.size)
+			eb := unsafe.Slice((*byte)(unsafe.Add(b, elem+s.offset)), s.size)
+			if !bytes.Equal(ea, eb) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// matchEqual reports whether the n elements of a can be paired with the n
+// elements of b so that every pair is equal. Equal is an equivalence, so
+// pairing greedily finds a matching whenever there is one.
+func matchEqual(n int, equal func(i, j int) bool) bool {
+	used := make([]bool, n)
+	for i := 0; i < n; i++ {
+		found := false
+		for j := 0; j < n; j++ {
+			if !used[j] && equal(i, j) {
+				used[j], found = true, true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,276 @@
+package anyhash_test
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"unsafe"
+
+	"github.com/hikitani/anyhash"
+	"github.com/hikitani/anyhash/internal/gentest"
+)
+
+// testFold hashes case-insensitively.
+type testFold string
+
+func (f testFold) AppendHash(b []byte) []byte {
+	return append(b, strings.ToLower(string(f))...)
+}
+
+type testEqualInner struct {
+	n    int8
+	f    float32
+	s    string
+	b    bool
+	fold testFold
+}
+
+type testEqualValue struct {
+	ID      int
+	Name    string
+	F       float64
+	C       complex64
+	Inner   testEqualInner
+	Ptr     *testEqualInner
+	PtrPtr  **int
+	Ints    []int16
+	Floats  [2]float64
+	Inners  []testEqualInner
+	Nested  [][]string
+	Attrs   map[string]float64
+	ByInner map[testEqualInner]int
+	Any     any
+	Ignored string  `anyhash:"-"`
+	Opt     []int   `anyhash:"omitempty"`
+	Set     []uint8 `anyhash:"unordered"`
+	private uint16
+}
+
+func TestEqual(t *testing.T) {
+	t.Run("Scalars", testEqualRandom[[3]int8]())
+	t.Run("Floats", testEqualRandom[[]float64]())
+	t.Run("Complex", testEqualRandom[[2]complex64]())
+	t.Run("Struct", testEqualRandom[testEqualInner]())
+	t.Run("Slices", testEqualRandom[[][]string]())
+	t.Run("Maps", testEqualRandom[map[testEqualInner][]float32]())
+	t.Run("Pointers", testEqualRandom[*[]**testEqualInner]())
+	t.Run("Interfaces", testEqualRandom[[]any]())
+	t.Run("Tags", testEqualRandom[struct {
+		A []int8   `anyhash:"unordered"`
+		B string   `anyhash:"omitempty"`
+		C *float64 `anyhash:"-"`
+	}]())
+	t.Run("All", testEqualRandom[testEqualValue]())
+}
+
+// testEqualRandom checks that random values equal by Equal hash equally with
+// every option, and that Equal agrees with itself on values built alike.
+func testEqualRandom[T any]() func(t *testing.T) {
+	return func(t *testing.T) {
+		hashers := map[string][]anyhash.Option{
+			"Default":   nil,
+			"Framed":    {anyhash.WithFraming()},
+			"RawFloats": {anyhash.WithRawFloats()},
+			"Portable":  {anyhash.WithPortable()},
+		}
+
+		typ := reflect.TypeOf((*T)(nil)).Elem()
+		for name, opts := range hashers {
+			h, err := anyhash.New[T](0, opts...)
+			if err != nil {
+				t.Fatalf("expected nil err, got %s", err)
+			}
+
+			n := 2000
+			if testing.Short() {
+				n = 300
+			}
+
+			r := rand.New(rand.NewSource(1))
+			equal := 0
+			for i := 0; i < n; i++ {
+				seed := r.Int63()
+				a := gentest.RandValue(rand.New(rand.NewSource(seed)), typ, 2).Interface().(T)
+				same := gentest.RandValue(rand.New(rand.NewSource(seed)), typ, 2).Interface().(T)
+				b := gentest.RandValue(r, typ, 2).Interface().(T)
+
+				// Values built alike share no memory.
+				if !h.Equal(a, same) {
+					t.Fatalf("%s: %+v is not equal to itself", name, a)
+				}
+				for _, v := range []T{same, b} {
+					if h.Equal(a, v) != h.Equal(v, a) {
+						t.Fatalf("%s: %+v, %+v: Equal is not symmetric", name, a, v)
+					}
+					if h.Equal(a, v) && h.GetHash128(a) != h.GetHash128(v) {
+						t.Fatalf("%s: %+v, %+v: equal values with different hashes", name, a, v)
+					}
+				}
+				if h.Equal(a, b) {
+					equal++
+				}
+			}
+			if equal == 0 && typ != reflect.TypeOf(testEqualValue{}) {
+				t.Fatalf("%s: no equal random values", name)
+			}
+		}
+	}
+}
+
+func TestEqualRules(t *testing.T) {
+	h, err := anyhash.New[testEqualValue](0)
+	if err != nil {
+		t.Fatalf("expected nil err, got %s", err)
+	}
+	hf, err := anyhash.New[testEqualValue](0, anyhash.WithFraming())
+	if err != nil {
+		t.Fatalf("expected nil err, got %s", err)
+	}
+	hr, err := anyhash.New[testEqualValue](0, anyhash.WithRawFloats())
+	if err != nil {
+		t.Fatalf("expected nil err, got %s", err)
+	}
+
+	one, two := 1, 2
+	pOne, pTwo := &one, &two
+	var pNil *int
+
+	// The bytes between Inner.n and Inner.f are padding.
+	var padded testEqualValue
+	*(*byte)(unsafe.Add(unsafe.Pointer(&padded.Inner.n), 1)) = 1
+
+	tests := []struct {
+		name          string
+		a, b          testEqualValue
+		equal, framed bool
+		raw           bool
+	}{
+		{"NaN", testEqualValue{F: math.NaN()}, testEqualValue{F: -math.NaN()}, true, true, false},
+		{"NegZero", testEqualValue{Floats: [2]float64{math.Copysign(0, -1)}}, testEqualValue{}, true, true, false},
+		{"Ignored", testEqualValue{Ignored: "a"}, testEqualValue{Ignored: "b"}, true, true, true},
+		{"Private", testEqualValue{private: 1}, testEqualValue{private: 2}, false, false, false},
+		{"Padding", padded, testEqualValue{}, true, true, true},
+		{"Hook", testEqualValue{Inner: testEqualInner{fold: "Ab"}}, testEqualValue{Inner: testEqualInner{fold: "aB"}}, true, true, true},
+		{"NilSlice", testEqualValue{Ints: []int16{}}, testEqualValue{}, true, false, true},
+		{"NilMap", testEqualValue{Attrs: map[string]float64{}}, testEqualValue{}, true, false, true},
+		{"MapNaN", testEqualValue{Attrs: map[string]float64{"a": math.NaN()}}, testEqualValue{Attrs: map[string]float64{"a": math.NaN()}}, true, true, true},
+		{"MapKeys", testEqualValue{Attrs: map[string]float64{"a": 1}}, testEqualValue{Attrs: map[string]float64{"b": 1}}, false, false, false},
+		{"PtrLevel", testEqualValue{PtrPtr: &pNil}, testEqualValue{}, false, false, false},
+		{"PtrValue", testEqualValue{PtrPtr: &pOne}, testEqualValue{PtrPtr: &pTwo}, false, false, false},
+		{"Unordered", testEqualValue{Set: []uint8{1, 2, 2}}, testEqualValue{Set: []uint8{2, 1, 2}}, true, true, true},
+		{"UnorderedCount", testEqualValue{Set: []uint8{1, 1, 2}}, testEqualValue{Set: []uint8{1, 2, 2}}, false, false, false},
+		{"OmitEmpty", testEqualValue{Opt: []int{}}, testEqualValue{}, false, false, false},
+		{"AnyTypes", testEqualValue{Any: 1}, testEqualValue{Any: int64(1)}, false, false, false},
+		{"AnyNaN", testEqualValue{Any: []float64{math.NaN()}}, testEqualValue{Any: []float64{math.NaN()}}, true, true, true},
+		{"AnyNil", testEqualValue{Any: []int(nil)}, testEqualValue{}, false, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, c := range []struct {
+				name string
+				h    *anyhash.AnyHasher[testEqualValue]
+				want bool
+			}{{"Default", h, tt.equal}, {"Framed", hf, tt.framed}, {"RawFloats", hr, tt.raw}} {
+				if got := c.h.Equal(tt.a, tt.b); got != c.want {
+					t.Fatalf("%s: got %t, want %t", c.name, got, c.want)
+				}
+				if c.want && c.h.GetHash128(tt.a) != c.h.GetHash128(tt.b) {
+					t.Fatalf("%s: equal values with different hashes", c.name)
+				}
+			}
+		})
+	}
+}
+
+func TestEqualCycles(t *testing.T) {
+	type node struct {
+		v    int
+		next *node
+	}
+	ring := func(vs ...int) *node {
+		first := &node{v: vs[0]}
+		last := first
+		for _, v := range vs[1:] {
+			last.next = &node{v: v}
+			last = last.next
+		}
+		last.next = first
+		return first
+	}
+
+	h, err := anyhash.New[*node](0, anyhash.WithFraming())
+	if err != nil {
+		t.Fatalf("expected nil err, got %s", err)
+	}
+
+	if !h.Equal(ring(1, 2), ring(1, 2)) {
+		t.Fatal("equal rings are not equal")
+	}
+	if h.Equal(ring(1, 1), ring(1, 1, 1)) {
+		t.Fatal("rings of different lengths are equal")
+	}
+	if h.Equal(ring(1, 2), ring(1, 3)) {
+		t.Fatal("different rings are equal")
+	}
+
+	a, b := ring(1), &node{v: 1, next: ring(1)}
+	if h.Equal(a, b) != (h.GetHash64(a) == h.GetHash64(b)) {
+		t.Fatal("Equal disagrees with hashes on cycles at different depths")
+	}
+
+	t.Run("Map", func(t *testing.T) {
+		type tree map[string]tree
+		cyclic := func() tree {
+			m := tree{}
+			m["x"] = m
+			return m
+		}
+
+		h, err := anyhash.New[tree](0, anyhash.WithFraming())
+		if err != nil {
+			t.Fatalf("expected nil err, got %s", err)
+		}
+		if !h.Equal(cyclic(), cyclic()) {
+			t.Fatal("equal cyclic maps are not equal")
+		}
+		if h.Equal(cyclic(), tree{"x": tree{}}) {
+			t.Fatal("different maps are equal")
+		}
+		a, b := cyclic(), tree{"x": cyclic()}
+		if h.Equal(a, b) != (h.GetHash64(a) == h.GetHash64(b)) {
+			t.Fatal("Equal disagrees with hashes on cycles at different depths")
+		}
+	})
+
+	t.Run("Slice", func(t *testing.T) {
+		type list []list
+		cyclic := func(n int) list {
+			s := make(list, n)
+			for i := range s {
+				s[i] = s
+			}
+			return s
+		}
+
+		h, err := anyhash.New[list](0, anyhash.WithFraming())
+		if err != nil {
+			t.Fatalf("expected nil err, got %s", err)
+		}
+		if !h.Equal(cyclic(2), cyclic(2)) {
+			t.Fatal("equal cyclic slices are not equal")
+		}
+		if h.Equal(cyclic(1), cyclic(2)) {
+			t.Fatal("cyclic slices of different lengths are equal")
+		}
+		if h.Equal(cyclic(1), list{list{}}) {
+			t.Fatal("different slices are equal")
+		}
+		a, b := cyclic(1), list{cyclic(1)}
+		if h.Equal(a, b) != (h.GetHash64(a) == h.GetHash64(b)) {
+			t.Fatal("Equal disagrees with hashes on cycles at different depths")
+		}
+	})
+}
@@ -0,0 +1,182 @@
+package anyhash
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/hikitani/anyhash/internal"
+	"github.com/hikitani/anyhash/internal/structtag"
+)
+
+// layoutVersion changes whenever the hashes of values change for reasons
+// other than their layout, such as a change of the hash functions.
+const layoutVersion = 1
+
+// Fingerprint returns a fingerprint of what h hashes: the paths, kinds, sizes
+// and pointer depths of the hashed fields in hashing order, the options, the
+// algorithm with the hash it gives a fixed input, which tells its keys apart,
+// and the version of the hashing scheme. It can be stored along with
+// persisted hashes to detect changes of T that break them. Fields tagged "-"
+// and the seed do not change it, though the SipHash24 key randomized hashers
+// get with their Seed does, as a key of the algorithm. Neither do changes of the bytes appended by
+// HashAppender types or of the dynamic types of interfaces.
+//
+// Hashers with the same fingerprint and seed are compatible: they hash the
+// same values equally, so filters and sketches made with them combine, and
+// one reads the binary forms made with the other. Binary forms do not store
+// the seed, which must be given again to read them.
+//
+// Fingerprints of portable hashers are the same on every platform.
+func (h *AnyHasher[T]) Fingerprint() string {
+	sum := h.fingerprint()
+	return hex.EncodeToString(sum[:])
+}
+
+func (h *AnyHasher[T]) fingerprint() (fp [16]byte) {
+	sum := sha256.Sum256([]byte(h.Layout()))
+	copy(fp[:], sum[:])
+	return fp
+}
+
+// hashesLike reports whether h and other are compatible.
+func (h *AnyHasher[T]) hashesLike(other *AnyHasher[T]) bool {
+	return h == other || h.seed == other.seed && h.fingerprint() == other.fingerprint()
+}
+
+// algorithmProbe is hashed by the algorithm of a hasher to tell its keys apart.
+const algorithmProbe = "anyhash algorithm probe"
+
+// Layout returns the description of what h hashes that Fingerprint is the
+// hash of. Comparing it with an old one shows what changed.
+func (h *AnyHasher[T]) Layout() string {
+	l := layout{opts: h.opts, described: map[reflect.Type]int{}}
+	fmt.Fprintf(&l.b, "anyhash layout v%d\n", layoutVersion)
+	fmt.Fprintf(&l.b, "framed=%t rawfloats=%t portable=%t", h.opts.framed, h.opts.rawFloats, h.opts.portable)
+	if h.opts.alg != nil {
+		// Algorithms of the same type may differ by their keys, which only
+		// show in their hashes.
+		fmt.Fprintf(&l.b, " algorithm=%T check=%016x", h.opts.alg, h.opts.alg.Hash([]byte(algorithmProbe), 0))
+	}
+	if !h.opts.portable {
+		fmt.Fprintf(&l.b, " bigendian=%t 64bit=%t", internal.BigEndian, is64Bit)
+	}
+	l.b.WriteByte('\n')
+
+	l.ref(reflect.TypeOf((*T)(nil)).Elem())
+	for i := 0; i < len(l.queue); i++ {
+		typ := l.queue[i]
+		fmt.Fprintf(&l.b, "type #%d %s\n", i, typ.Kind())
+		l.fill(typ, "", 0)
+	}
+	return l.b.String()
+}
+
+// layout describes the plans of a hasher step by step, following the
+// decisions of hashBuilder. Types with plans of their own are described once
+// and referred to by number, as their names do not change the hashes.
+type layout struct {
+	opts      options
+	b         strings.Builder
+	described map[reflect.Type]int
+	queue     []reflect.Type
+}
+
+func (l *layout) step(path string, ptrDepth int, format string, args ...any) {
+	if path == "" {
+		path = "."
+	}
+	fmt.Fprintf(&l.b, "\t%s ", path)
+	fmt.Fprintf(&l.b, format, args...)
+	if ptrDepth != 0 {
+		fmt.Fprintf(&l.b, " ptr=%d", ptrDepth)
+	}
+	l.b.WriteByte('\n')
+}
+
+// ref returns the number of typ, describing its plan later if it is new.
+func (l *layout) ref(typ reflect.Type) string {
+	n, ok := l.described[typ]
+	if !ok {
+		n = len(l.queue)
+		l.described[typ] = n
+		l.queue = append(l.queue, typ)
+	}
+	return fmt.Sprintf("#%d", n)
+}
+
+func (l *layout) fill(typ reflect.Type, path string, ptrDepth int) {
+	if hasHashHook(typ) {
+		l.step(path, ptrDepth, "hook %s", typeIdent(typ))
+		return
+	}
+
+	switch typ.Kind() {
+	case reflect.String:
+		l.step(path, ptrDepth, "string")
+	case reflect.Slice:
+		if needsElemPlan(typ.Elem()) {
+			l.step(path, ptrDepth, "slice of %s", l.ref(typ.Elem()))
+			return
+		}
+		l.step(path, ptrDepth, "slice block %s", l.scalars(typ.Elem()))
+	case reflect.Array:
+		if needsElemPlan(typ.Elem()) {
+			l.step(path, ptrDepth, "array %d of %s", typ.Len(), l.ref(typ.Elem()))
+			return
+		}
+		n, _, _ := getLenAndElemSzArray(typ)
+		l.step(path, ptrDepth, "array %d block %s", n, l.scalars(innermostElem(typ)))
+	case reflect.Struct:
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			tag, _ := structtag.Parse(field.Tag.Get("anyhash"))
+			if tag.Skip {
+				continue
+			}
+
+			fieldPath := path + "." + field.Name
+			if tag.OmitEmpty {
+				l.step(fieldPath, 0, "omitempty")
+			}
+			if tag.Unordered {
+				l.step(fieldPath, 0, "unordered %s of %s", field.Type.Kind(), l.ref(field.Type.Elem()))
+				continue
+			}
+			l.fill(field.Type, fieldPath, ptrDepth)
+		}
+	case reflect.Map:
+		l.step(path, ptrDepth, "map of %s to %s", l.ref(typ.Key()), l.ref(typ.Elem()))
+	case reflect.Pointer:
+		if typ.Elem().Kind() == reflect.Struct {
+			l.step(path, ptrDepth+1, "struct %s", l.ref(typ.Elem()))
+			return
+		}
+		l.fill(typ.Elem(), path, ptrDepth+1)
+	case reflect.Interface:
+		l.step(path, ptrDepth, "interface")
+	default:
+		l.step(path, ptrDepth, "%s", l.scalars(typ))
+	}
+}
+
+// scalars describes the bytes written for the pointer-free type typ.
+func (l *layout) scalars(typ reflect.Type) string {
+	var b strings.Builder
+	for i, s := range scalarsOf(typ, 0, nil) {
+		if i != 0 {
+			b.WriteByte(',')
+		}
+		size := s.size
+		if l.opts.portable && (s.kind == scalarInt || s.kind == scalarUint) {
+			size = 8
+		}
+		fmt.Fprintf(&b, "%s/%d", [...]string{"fixed", "int", "uint", "float"}[s.kind], size)
+	}
+	if b.Len() == 0 {
+		return "empty"
+	}
+	return b.String()
+}
@@ -0,0 +1,151 @@
+package anyhash
+
+import (
+	"strings"
+	"testing"
+)
+
+type testFingerprint struct {
+	ID    int
+	Name  string
+	Next  *testFingerprint
+	Tags  []string `anyhash:"unordered"`
+	Point struct {
+		X int8
+		Y float64
+	}
+	Attrs map[string][]float32
+	Frac  testFrac
+	Grid  [2][3]uint16
+	Ptr   **int
+	Note  string      `anyhash:"omitempty"`
+	Cache map[int]int `anyhash:"-"`
+}
+
+func fingerprintOf[T any](t *testing.T, opts ...Option) string {
+	h, err := New[T](0, opts...)
+	if err != nil {
+		t.Fatalf("expected nil err, got %s", err)
+	}
+	return h.Fingerprint()
+}
+
+func TestFingerprint(t *testing.T) {
+	base := fingerprintOf[testFingerprint](t, WithPortable())
+
+	t.Run("Golden", func(t *testing.T) {
+		// Portable fingerprints are the same on every platform, and only
+		// change along with the hashes.
+		if want := "e4130cdf2e0a95d6f170d7d546b6b93f"; base != want {
+			h, _ := New[testFingerprint](0, WithPortable())
+			t.Fatalf("got %s, want %s, layout:\n%s", base, want, h.Layout())
+		}
+	})
+
+	t.Run("Same", func(t *testing.T) {
+		type skipped struct {
+			ID    int
+			Name  string
+			Next  *skipped
+			Tags  []string `anyhash:"unordered"`
+			Point struct {
+				X int8
+				Y float64
+			}
+			Attrs map[string][]float32
+			Frac  testFrac
+			Grid  [2][3]uint16
+			Ptr   **int
+			Note  string `anyhash:"omitempty"`
+			Skip  func() `anyhash:"-"`
+		}
+
+		seeded, err := New[testFingerprint](42, WithPortable())
+		if err != nil {
+			t.Fatalf("expected nil err, got %s", err)
+		}
+
+		for name, fp := range map[string]string{
+			"Seed":    seeded.Fingerprint(),
+			"Skipped": fingerprintOf[skipped](t, WithPortable()),
+		} {
+			if fp != base {
+				t.Fatalf("%s: got %s, want %s", name, fp, base)
+			}
+		}
+	})
+
+	t.Run("Changed", func(t *testing.T) {
+		type reordered struct {
+			Name string
+			ID   int
+		}
+		type renamed struct {
+			Key  int
+			Name string
+		}
+		type retyped struct {
+			ID   int32
+			Name string
+		}
+		type omitEmpty struct {
+			ID   int
+			Name string `anyhash:"omitempty"`
+		}
+		type pointer struct {
+			ID   *int
+			Name string
+		}
+		type plain struct {
+			ID   int
+			Name string
+		}
+
+		seen := map[string]string{"Base": base}
+		for name, fp := range map[string]string{
+			"Plain":     fingerprintOf[plain](t, WithPortable()),
+			"Reordered": fingerprintOf[reordered](t, WithPortable()),
+			"Renamed":   fingerprintOf[renamed](t, WithPortable()),
+			"Retyped":   fingerprintOf[retyped](t, WithPortable()),
+			"OmitEmpty": fingerprintOf[omitEmpty](t, WithPortable()),
+			"Pointer":   fingerprintOf[pointer](t, WithPortable()),
+			"Framed":    fingerprintOf[plain](t, WithPortable(), WithFraming()),
+			"RawFloats": fingerprintOf[plain](t, WithPortable(), WithRawFloats()),
+			"Native":    fingerprintOf[plain](t),
+			"Algorithm": fingerprintOf[plain](t, WithPortable(), WithAlgorithm(XXHash64)),
+			"Key1":      fingerprintOf[plain](t, WithPortable(), WithAlgorithm(SipHash24([16]byte{1}))),
+			"Key2":      fingerprintOf[plain](t, WithPortable(), WithAlgorithm(SipHash24([16]byte{2}))),
+		} {
+			for other, ofp := range seen {
+				if fp == ofp {
+					t.Fatalf("%s and %s: same fingerprint %s", name, other, fp)
+				}
+			}
+			seen[name] = fp
+		}
+	})
+
+	t.Run("Layout", func(t *testing.T) {
+		h, err := New[testFingerprint](0, WithPortable())
+		if err != nil {
+			t.Fatalf("expected nil err, got %s", err)
+		}
+
+		layout := h.Layout()
+		for _, line := range []string{
+			"\t.ID int/8\n",
+			"\t.Next struct #0 ptr=1\n",
+			"\t.Point.X fixed/1\n",
+			"\t.Grid array 6 block fixed/2\n",
+			"\t.Ptr int/8 ptr=2\n",
+			"\t.Note omitempty\n",
+		} {
+			if !strings.Contains(layout, line) {
+				t.Fatalf("layout has no line %q:\n%s", line, layout)
+			}
+		}
+		if strings.Contains(layout, "Cache") {
+			t.Fatalf("layout has a skipped field:\n%s", layout)
+		}
+	})
+}
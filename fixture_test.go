@@ -0,0 +1,99 @@
+package anyhash
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// testKey is the value hashed by the tests of the structures built on
+// hashers.
+type testKey struct {
+	Tenant string
+	ID     uint64
+	Tags   []string
+}
+
+// testKeys returns the distinct keys numbered from from to to. Keys of the
+// same number are equal but share no memory.
+func testKeys(from, to int) []testKey {
+	keys := make([]testKey, 0, to-from)
+	for i := from; i < to; i++ {
+		keys = append(keys, testKey{
+			Tenant: "tenant" + strconv.Itoa(i%13),
+			ID:     uint64(i / 13),
+			Tags:   []string{"t" + strconv.Itoa(i%7)},
+		})
+	}
+	return keys
+}
+
+// testHasherOptions are the options of the hashers that the tests of the
+// structures built on hashers run with: portable hashes are the same on
+// every platform, and native ones are 32-bit on 32-bit platforms.
+var testHasherOptions = map[string][]Option{
+	"Portable": {WithPortable()},
+	"Native":   nil,
+}
+
+// newTestHasher returns a hasher of T with seed 0 and opts.
+func newTestHasher[T any](t testing.TB, opts ...Option) *AnyHasher[T] {
+	h, err := New[T](0, opts...)
+	if err != nil {
+		t.Fatalf("expected nil err, got %s", err)
+	}
+	return h
+}
+
+// must returns a function returning v that fails the test if err is not
+// nil, to check the results of constructors where they are used.
+func must[V any](v V, err error) func(testing.TB) V {
+	return func(t testing.TB) V {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("expected nil err, got %s", err)
+		}
+		return v
+	}
+}
+
+// testBinaryCase is invalid binary data and a part of the error it must give.
+type testBinaryCase struct {
+	data []byte
+	err  string
+}
+
+// testUnmarshalErrors checks that unmarshal rejects data, the binary form made
+// with a portable hasher, when it is empty, cut or of another magic, or read
+// with a hasher with other options or another seed, as well as the cases of
+// extra.
+func testUnmarshalErrors[S any](t *testing.T, data []byte, unmarshal func(*AnyHasher[testKey], []byte) (S, error), extra map[string]testBinaryCase) {
+	tests := map[string]testBinaryCase{
+		"Empty":     {nil, "invalid"},
+		"Magic":     {append([]byte("XXXX"), data[4:]...), "invalid"},
+		"Truncated": {data[:len(data)-1], "invalid"},
+	}
+	for name, tt := range extra {
+		tests[name] = tt
+	}
+
+	h := newTestHasher[testKey](t, WithPortable())
+	for name, tt := range tests {
+		if _, err := unmarshal(h, tt.data); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Fatalf("%s: got err %v, want %q", name, err, tt.err)
+		}
+	}
+
+	framed := newTestHasher[testKey](t, WithPortable(), WithFraming())
+	if _, err := unmarshal(framed, data); err == nil || !strings.Contains(err.Error(), "fingerprint") {
+		t.Fatalf("Fingerprint: got err %v, want %q", err, "fingerprint")
+	}
+
+	seeded, err := New[testKey](1, WithPortable())
+	if err != nil {
+		t.Fatalf("expected nil err, got %s", err)
+	}
+	if _, err := unmarshal(seeded, data); err == nil || !strings.Contains(err.Error(), "seed") {
+		t.Fatalf("Seed: got err %v, want %q", err, "seed")
+	}
+}
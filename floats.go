@@ -0,0 +1,162 @@
+package anyhash
+
+import (
+	"math"
+	"reflect"
+	"unsafe"
+)
+
+var (
+	canonicalNaN64 = math.Float64bits(math.NaN())
+	canonicalNaN32 = math.Float32bits(float32(math.NaN()))
+)
+
+// canonical64 maps -0 to 0 and every NaN to one NaN, so that bits of floats
+// equal by == are equal too. Other values keep their bits.
+func canonical64(b uint64) uint64 {
+	switch {
+	case b == 1<<63:
+		return 0
+	case b&^(1<<63) > 0x7ff0000000000000:
+		return canonicalNaN64
+	}
+	return b
+}
+
+func canonical32(b uint32) uint32 {
+	switch {
+	case b == 1<<31:
+		return 0
+	case b&^(1<<31) > 0x7f800000:
+		return canonicalNaN32
+	}
+	return b
+}
+
+// floatPos is the offset of a float inside a value.
+type floatPos struct {
+	offset uintptr
+	is64   bool
+}
+
+// floatsOf returns the offsets of all floats, including the parts of complex
+// numbers, inside a value of the pointer-free type typ.
+func floatsOf(typ reflect.Type, offset uintptr) []floatPos {
+	switch typ.Kind() {
+	case reflect.Float32:
+		return []floatPos{{offset, false}}
+	case reflect.Float64:
+		return []floatPos{{offset, true}}
+	case reflect.Complex64:
+		return []floatPos{{offset, false}, {offset + 4, false}}
+	case reflect.Complex128:
+		return []floatPos{{offset, true}, {offset + 8, true}}
+	case reflect.Array:
+		elem := floatsOf(typ.Elem(), 0)
+		if len(elem) == 0 {
+			return nil
+		}
+		var floats []floatPos
+		for i := 0; i < typ.Len(); i++ {
+			for _, f := range elem {
+				floats = append(floats, floatPos{offset + uintptr(i)*typ.Elem().Size() + f.offset, f.is64})
+			}
+		}
+		return floats
+	case reflect.Struct:
+		var floats []floatPos
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			floats = append(floats, floatsOf(field.Type, offset+field.Offset)...)
+		}
+		return floats
+	}
+	return nil
+}
+
+// canonicalBlock returns the n elements at p with their floats made canonical.
+// Unless one of the floats changes, it is p itself, otherwise a copy.
+func canonicalBlock(p unsafe.Pointer, n int, elemSz uintptr, floats []floatPos) unsafe.Pointer {
+	var block []byte
+	for i := 0; i < n; i++ {
+		elem := uintptr(i) * elemSz
+		for _, f := range floats {
+			fp := unsafe.Add(p, elem+f.offset)
+			if f.is64 {
+				b := *(*uint64)(fp)
+				if c := canonical64(b); c != b {
+					if block == nil {
+						block = copyBlock(p, uintptr(n)*elemSz)
+					}
+					*(*uint64)(unsafe.Pointer(&block[elem+f.offset])) = c
+				}
+				continue
+			}
+			b := *(*uint32)(fp)
+			if c := canonical32(b); c != b {
+				if block == nil {
+					block = copyBlock(p, uintptr(n)*elemSz)
+				}
+				*(*uint32)(unsafe.Pointer(&block[elem+f.offset])) = c
+			}
+		}
+	}
+
+	if block == nil {
+		return p
+	}
+	return unsafe.Pointer(&block[0])
+}
+
+func copyBlock(p unsafe.Pointer, sz uintptr) []byte {
+	block := make([]byte, sz)
+	copy(block, unsafe.Slice((*byte)(p), sz))
+	return block
+}
+
+// floatGetter hashes a float or complex number by its canonical bits.
+type floatGetter struct {
+	offset   uintptr
+	ptrDepth int
+	floats   []floatPos
+	elemSz   uintptr
+}
+
+func (f *floatGetter) writeTo(w *writer, p unsafe.Pointer) {
+	np := deref(w, p, f.offset, f.ptrDepth)
+	if np == nil {
+		return
+	}
+
+	var v [2]uint64
+	vp := noescape(unsafe.Pointer(&v))
+	for _, fp := range f.floats {
+		if fp.is64 {
+			*(*uint64)(unsafe.Add(vp, fp.offset)) = canonical64(*(*uint64)(unsafe.Add(np, fp.offset)))
+		} else {
+			*(*uint32)(unsafe.Add(vp, fp.offset)) = canonical32(*(*uint32)(unsafe.Add(np, fp.offset)))
+		}
+	}
+	w.write(vp, f.elemSz)
+}
+
+func (f *floatGetter) equal(e *equaler, a, b unsafe.Pointer) bool {
+	na, nb, equal, done := derefEqual(a, b, f.offset, f.ptrDepth)
+	if done {
+		return equal
+	}
+
+	return floatsEqual(na, nb, f.floats)
+}
+
+// floatsEqual reports whether the floats at a and b have equal canonical bits.
+func floatsEqual(a, b unsafe.Pointer, floats []floatPos) bool {
+	for _, f := range floats {
+		fa, fb := unsafe.Add(a, f.offset), unsafe.Add(b, f.offset)
+		if f.is64 && canonical64(*(*uint64)(fa)) != canonical64(*(*uint64)(fb)) ||
+			!f.is64 && canonical32(*(*uint32)(fa)) != canonical32(*(*uint32)(fb)) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,55 @@
+package anyhash
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCanonicalFloats(t *testing.T) {
+	negZero := math.Copysign(0, -1)
+	nan1 := math.NaN()
+	nan2 := math.Float64frombits(0x7ff0000000000001)
+	nan3 := math.Float64frombits(0xfff8000000000000)
+	negZero32 := float32(negZero)
+	nan32 := math.Float32frombits(0x7f800001)
+	type mixed struct {
+		a int32
+		f float32
+		c complex128
+	}
+
+	t.Run("Float64", testEqual(nil, 0, negZero))
+	t.Run("Float64NaN", testEqual(nil, nan1, nan2, nan3))
+	t.Run("Float32", testEqual(nil, float32(0), negZero32))
+	t.Run("Float32NaN", testEqual(nil, float32(nan1), nan32))
+	t.Run("Complex64", testEqual(nil, complex(float32(0), negZero32), complex(negZero32, 0)))
+	t.Run("Complex128", testEqual(nil, complex(nan1, negZero), complex(nan2, 0)))
+	t.Run("Pointer[Float64]", testEqual(nil, &negZero, new(float64)))
+	t.Run("Slice[Float64]", testEqual(nil, []float64{0, nan1, 1}, []float64{negZero, nan2, 1}))
+	t.Run("Array[Float32]", testEqual(nil, [3]float32{0, 1, float32(nan1)}, [3]float32{negZero32, 1, nan32}))
+	t.Run("Array[Array[Float64]]", testEqual(nil, [2][2]float64{{0}, {nan1}}, [2][2]float64{{negZero}, {nan3}}))
+	t.Run("Slice[Array[Float64]]", testEqual(nil, [][2]float64{{1, 0}, {nan1, 2}}, [][2]float64{{1, negZero}, {nan2, 2}}))
+	t.Run("Slice[Struct]", testEqual(nil,
+		[]mixed{{1, 0, complex(0, 1)}, {2, 1, complex(nan1, 0)}},
+		[]mixed{{1, negZero32, complex(negZero, 1)}, {2, 1, complex(nan2, negZero)}},
+	))
+	t.Run("Map", testEqual(nil, map[float64]int{0: 1}, map[float64]int{negZero: 1}))
+	t.Run("Interface", testEqual(nil, any(0.0), any(negZero)))
+
+	t.Run("Differ", testDiffer(nil, 0, 1, nan1, math.Inf(1), math.Inf(-1)))
+	t.Run("Raw", testDiffer([]Option{WithRawFloats()}, 0, negZero, nan1, nan2))
+	t.Run("RawSlice", testDiffer([]Option{WithRawFloats()}, []float64{0}, []float64{negZero}))
+
+	t.Run("NormalValues", testType([]float32{1, 2}, []byte{0, 0, 128, 63, 0, 0, 0, 64}))
+	t.Run("Unchanged", func(t *testing.T) {
+		h, err := New[[]float64](0)
+		if err != nil {
+			t.Fatalf("expected nil err, got %s", err)
+		}
+		v := []float64{negZero, nan2}
+		h.GetHash(v)
+		if math.Float64bits(v[0]) != 1<<63 || math.Float64bits(v[1]) != 0x7ff0000000000001 {
+			t.Fatal("hashing changed the slice")
+		}
+	})
+}
@@ -0,0 +1,266 @@
+package anyhash
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/bits"
+	"sort"
+)
+
+// BinaryFuseFilter is a static set of values of any type hashable by
+// AnyHasher that answers whether it contains a value with false positives
+// but without false negatives. It is the binary fuse filter of Graf and
+// Lemire with 8-bit fingerprints: for a false positive rate of 1/256 it takes
+// 9 to 10 bits per value, fewer the more values there are, where a
+// BloomFilter takes 11.5, and a lookup reads three bytes. Values cannot be
+// added after construction.
+type BinaryFuseFilter[T any] struct {
+	h *AnyHasher[T]
+	fuseShape
+	fingerprints []uint8
+}
+
+// fuseShape places hashes in the fingerprints of a filter.
+type fuseShape struct {
+	seed          uint64
+	segmentLength uint32
+	segmentCount  uint32
+}
+
+// maxFuseAttempts bounds the seeds tried by NewBinaryFuseFilter. Peeling
+// fails with a probability well below one half, so running out of seeds means
+// a bug rather than bad luck.
+const maxFuseAttempts = 100
+
+// NewBinaryFuseFilter returns the filter of values hashed with h. Equal values
+// are added once. Construction hashes every value once, then tries seeds
+// mixed into the hashes until the values peel.
+func NewBinaryFuseFilter[T any](h *AnyHasher[T], values []T) (*BinaryFuseFilter[T], error) {
+	hashes := make([]uint64, len(values))
+	for i, v := range values {
+		hashes[i] = h.GetHash64(v)
+	}
+
+	// Values with equal hashes cannot be peeled apart, and they are the same
+	// value as far as the filter can tell.
+	sortHashes(hashes)
+	n := 0
+	for i, hash := range hashes {
+		if i == 0 || hash != hashes[n-1] {
+			hashes[n] = hash
+			n++
+		}
+	}
+	hashes = hashes[:n]
+
+	f := &BinaryFuseFilter[T]{h: h}
+	f.init(n)
+	b := fuseBuilder{
+		counts: make([]uint8, len(f.fingerprints)),
+		xors:   make([]uint64, len(f.fingerprints)),
+		stack:  make([]fuseEntry, 0, n),
+	}
+	for attempt := 0; ; attempt++ {
+		if attempt == maxFuseAttempts {
+			return nil, errors.New("anyhash: binary fuse filter construction failed")
+		}
+		f.seed = combineHashes(fuseSeed, uint64(attempt))
+		if b.peel(&f.fuseShape, hashes) {
+			break
+		}
+	}
+
+	// Values peeled last are assigned first. The position left to a value is
+	// still zero then, and no value assigned later changes the other two.
+	for i := len(b.stack) - 1; i >= 0; i-- {
+		e := b.stack[i]
+		h0, h1, h2 := f.positions(e.hash)
+		f.fingerprints[e.pos] = fuseFingerprint(e.hash) ^ f.fingerprints[h0] ^ f.fingerprints[h1] ^ f.fingerprints[h2]
+	}
+	return f, nil
+}
+
+const fuseSeed = 0x726a_5f73_6675_7365
+
+// init sizes the filter for n values with the parameters of the reference
+// implementation for three hashes.
+func (f *BinaryFuseFilter[T]) init(n int) {
+	f.segmentLength = 4
+	if n > 0 {
+		f.segmentLength = 1 << int(math.Floor(math.Log(float64(n))/math.Log(3.33)+2.25))
+	}
+	if f.segmentLength > 1<<18 {
+		f.segmentLength = 1 << 18
+	}
+
+	capacity := 0
+	if n > 1 {
+		factor := math.Max(1.125, 0.875+0.25*math.Log(1e6)/math.Log(float64(n)))
+		capacity = int(math.Round(float64(n) * factor))
+	}
+	segments := (capacity+int(f.segmentLength)-1)/int(f.segmentLength) - 2
+	if segments < 1 {
+		segments = 1
+	}
+	f.segmentCount = uint32(segments)
+	f.fingerprints = make([]uint8, (f.segmentCount+2)*f.segmentLength)
+}
+
+// Contains reports whether v may be in the filter. It is false for values
+// that are not with a probability of about 255/256, and true for values that
+// are.
+func (f *BinaryFuseFilter[T]) Contains(v T) bool {
+	hash := combineHashes(f.seed, f.h.GetHash64(v))
+	h0, h1, h2 := f.positions(hash)
+	return fuseFingerprint(hash)^f.fingerprints[h0]^f.fingerprints[h1]^f.fingerprints[h2] == 0
+}
+
+// Bits returns the size of the filter in bits.
+func (f *BinaryFuseFilter[T]) Bits() int {
+	return len(f.fingerprints) * 8
+}
+
+// positions returns the three positions of hash, one in each of three
+// consecutive segments.
+func (f *fuseShape) positions(hash uint64) (uint32, uint32, uint32) {
+	hi, _ := bits.Mul64(hash, uint64(f.segmentCount*f.segmentLength))
+	mask := f.segmentLength - 1
+	h0 := uint32(hi)
+	h1 := (h0 + f.segmentLength) ^ uint32(hash>>18)&mask
+	h2 := (h0 + 2*f.segmentLength) ^ uint32(hash)&mask
+	return h0, h1, h2
+}
+
+func fuseFingerprint(hash uint64) uint8 {
+	return uint8(hash ^ hash>>32)
+}
+
+// fuseBuilder peels values off the positions of a filter: a position that
+// only one value hashes to can be left to that value, and the value no longer
+// takes the other two.
+type fuseBuilder struct {
+	counts []uint8
+	xors   []uint64
+	queue  []uint32
+	stack  []fuseEntry
+}
+
+// fuseEntry is a peeled value and the position left to it.
+type fuseEntry struct {
+	hash uint64
+	pos  uint32
+}
+
+// peel peels the distinct key hashes mixed with the seed of f into b.stack and
+// reports whether all of them peeled.
+func (b *fuseBuilder) peel(f *fuseShape, keyHashes []uint64) bool {
+	for i := range b.counts {
+		b.counts[i], b.xors[i] = 0, 0
+	}
+	b.queue, b.stack = b.queue[:0], b.stack[:0]
+
+	for _, kh := range keyHashes {
+		hash := combineHashes(f.seed, kh)
+		h0, h1, h2 := f.positions(hash)
+		for _, pos := range [3]uint32{h0, h1, h2} {
+			// Counts above 255 would wrap, but such positions cannot peel
+			// anyway, and construction fails long before they do.
+			if b.counts[pos] == math.MaxUint8 {
+				return false
+			}
+			b.counts[pos]++
+			b.xors[pos] ^= hash
+		}
+	}
+
+	for pos, count := range b.counts {
+		if count == 1 {
+			b.queue = append(b.queue, uint32(pos))
+		}
+	}
+	for len(b.queue) > 0 {
+		pos := b.queue[len(b.queue)-1]
+		b.queue = b.queue[:len(b.queue)-1]
+		if b.counts[pos] != 1 {
+			continue
+		}
+
+		hash := b.xors[pos]
+		b.stack = append(b.stack, fuseEntry{hash: hash, pos: pos})
+		h0, h1, h2 := f.positions(hash)
+		for _, other := range [3]uint32{h0, h1, h2} {
+			b.counts[other]--
+			b.xors[other] ^= hash
+			if b.counts[other] == 1 {
+				b.queue = append(b.queue, other)
+			}
+		}
+	}
+	return len(b.stack) == len(keyHashes)
+}
+
+// sortHashes sorts hashes, by radix unless there are few of them.
+func sortHashes(hashes []uint64) {
+	if len(hashes) < 1<<10 {
+		sort.Slice(hashes, func(i, j int) bool { return hashes[i] < hashes[j] })
+		return
+	}
+
+	buf := make([]uint64, len(hashes))
+	src, dst := hashes, buf
+	for shift := 0; shift < 64; shift += 8 {
+		var offsets [256]int
+		for _, h := range src {
+			offsets[byte(h>>shift)]++
+		}
+		sum := 0
+		for i, n := range offsets {
+			offsets[i], sum = sum, sum+n
+		}
+		for _, h := range src {
+			dst[offsets[byte(h>>shift)]] = h
+			offsets[byte(h>>shift)]++
+		}
+		src, dst = dst, src
+	}
+	// An even number of passes leaves the result in hashes.
+}
+
+// fuseMagic starts the binary form of a BinaryFuseFilter, whose fields are
+// the seed, the segment length and count and the fingerprints.
+const fuseMagic = "AHF1"
+
+// MarshalBinary returns the binary form of f, which UnmarshalBinaryFuseFilter
+// reads back.
+func (f *BinaryFuseFilter[T]) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 0, binaryHeaderSize+16+len(f.fingerprints))
+	b = f.h.appendHeader(b, fuseMagic)
+	b = appendUint64(b, f.seed)
+	b = appendUint32(b, f.segmentLength)
+	b = appendUint32(b, f.segmentCount)
+	return append(b, f.fingerprints...), nil
+}
+
+// UnmarshalBinaryFuseFilter returns the filter in the binary form data, made
+// by MarshalBinary of a filter with a hasher compatible with h, as told by
+// Fingerprint.
+func UnmarshalBinaryFuseFilter[T any](h *AnyHasher[T], data []byte) (*BinaryFuseFilter[T], error) {
+	data, err := h.readHeader(data, fuseMagic, "binary fuse filter", 16)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &BinaryFuseFilter[T]{h: h, fuseShape: fuseShape{
+		seed:          binary.LittleEndian.Uint64(data),
+		segmentLength: binary.LittleEndian.Uint32(data[8:]),
+		segmentCount:  binary.LittleEndian.Uint32(data[12:]),
+	}}
+	data = data[16:]
+	if f.segmentLength == 0 || f.segmentLength&(f.segmentLength-1) != 0 || f.segmentLength > 1<<18 ||
+		f.segmentCount == 0 || uint64(len(data)) != (uint64(f.segmentCount)+2)*uint64(f.segmentLength) {
+		return nil, errors.New("anyhash: invalid binary fuse filter data")
+	}
+	f.fingerprints = append([]uint8(nil), data...)
+	return f, nil
+}
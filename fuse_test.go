@@ -0,0 +1,151 @@
+package anyhash
+
+import (
+	"testing"
+)
+
+func TestBinaryFuseFilter(t *testing.T) {
+	n := 100000
+	if testing.Short() {
+		n = 20000
+	}
+	added, absent := testKeys(0, n), testKeys(n, 3*n)
+	h := newTestHasher[testKey](t, WithPortable())
+
+	t.Run("FalsePositiveRate", func(t *testing.T) {
+		for name, opts := range testHasherOptions {
+			f, err := NewBinaryFuseFilter(newTestHasher[testKey](t, opts...), added)
+			if err != nil {
+				t.Fatalf("%s: expected nil err, got %s", name, err)
+			}
+			for _, k := range added {
+				if !f.Contains(k) {
+					t.Fatalf("%s: false negative %v", name, k)
+				}
+			}
+
+			positives := 0
+			for _, k := range absent {
+				if f.Contains(k) {
+					positives++
+				}
+			}
+			if got := float64(positives) / float64(len(absent)); got > 1.3/256 {
+				t.Fatalf("%s: got false positive rate %g, want about %g", name, got, 1.0/256)
+			}
+			if got := float64(f.Bits()) / float64(n); got > 10 {
+				t.Fatalf("%s: got %.2f bits per value, want at most 10", name, got)
+			}
+		}
+	})
+
+	t.Run("Sizes", func(t *testing.T) {
+		for _, size := range []int{0, 1, 2, 3, 10, 100, 1000} {
+			f := must(NewBinaryFuseFilter(h, added[:size]))(t)
+			for _, k := range added[:size] {
+				if !f.Contains(k) {
+					t.Fatalf("%d values: false negative %v", size, k)
+				}
+			}
+		}
+	})
+
+	t.Run("Duplicates", func(t *testing.T) {
+		keys := append(testKeys(0, 1000), testKeys(0, 1000)...)
+		f := must(NewBinaryFuseFilter(h, keys))(t)
+		for _, k := range keys {
+			if !f.Contains(k) {
+				t.Fatalf("false negative %v", k)
+			}
+		}
+	})
+
+	t.Run("Binary", func(t *testing.T) {
+		f := must(NewBinaryFuseFilter(h, added[:1000]))(t)
+		data, err := f.MarshalBinary()
+		if err != nil {
+			t.Fatalf("expected nil err, got %s", err)
+		}
+
+		g, err := UnmarshalBinaryFuseFilter(h, data)
+		if err != nil {
+			t.Fatalf("expected nil err, got %s", err)
+		}
+		for _, k := range added[:1000] {
+			if !g.Contains(k) {
+				t.Fatalf("false negative %v", k)
+			}
+		}
+		for _, k := range absent[:10000] {
+			if g.Contains(k) != f.Contains(k) {
+				t.Fatalf("%v: filters disagree", k)
+			}
+		}
+
+		testUnmarshalErrors(t, data, UnmarshalBinaryFuseFilter[testKey], nil)
+	})
+}
+
+// benchFilters compares the binary fuse filter of values with a Bloom filter
+// of about the same false positive rate.
+func benchFilters[T any](b *testing.B, values, queries []T) {
+	h := newTestHasher[T](b)
+	n := float64(len(values))
+
+	fuse, err := NewBinaryFuseFilter(h, values)
+	if err != nil {
+		b.Fatalf("expected nil err, got %s", err)
+	}
+	bloom := NewBloomFilter(h, len(values), 1.0/256)
+	for _, v := range values {
+		bloom.Add(v)
+	}
+
+	b.Run("BinaryFuse/Contains", func(b *testing.B) {
+		b.ReportMetric(float64(fuse.Bits())/n, "bits/value")
+		for i := 0; i < b.N; i++ {
+			fuse.Contains(queries[i%len(queries)])
+		}
+	})
+
+	b.Run("Bloom/Contains", func(b *testing.B) {
+		b.ReportMetric(float64(bloom.Bits())/n, "bits/value")
+		for i := 0; i < b.N; i++ {
+			bloom.Contains(queries[i%len(queries)])
+		}
+	})
+
+	b.Run("BinaryFuse/Build", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := NewBinaryFuseFilter(h, values); err != nil {
+				b.Fatalf("expected nil err, got %s", err)
+			}
+		}
+	})
+
+	b.Run("Bloom/Build", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			f := NewBloomFilter(h, len(values), 1.0/256)
+			for _, v := range values {
+				f.Add(v)
+			}
+		}
+	})
+}
+
+func BenchmarkFilters(b *testing.B) {
+	const n = 1000000
+	pairs := make([][2]uint32, n+1024)
+	for i := range pairs {
+		pairs[i] = [2]uint32{uint32(i % 1000), uint32(i / 1000)}
+	}
+	b.Run("Pairs", func(b *testing.B) {
+		benchFilters(b, pairs[:n], pairs[n/2:n/2+1024])
+	})
+
+	b.Run("Keys", func(b *testing.B) {
+		benchFilters(b, testKeys(0, n/10), testKeys(n/20, n/20+1024))
+	})
+}
@@ -0,0 +1,103 @@
+package anyhash
+
+import (
+	"math"
+	"math/bits"
+	"testing"
+)
+
+func TestGetHash128(t *testing.T) {
+	type record struct {
+		id   int
+		name string
+		tags []string `anyhash:"unordered"`
+		m    map[string]float64
+		next *record
+	}
+
+	for _, opts := range [][]Option{nil, {WithFraming()}} {
+		name := "Unframed"
+		if opts != nil {
+			name = "Framed"
+		}
+		t.Run(name, func(t *testing.T) {
+			t.Run("Equal", testEqual128(opts,
+				record{id: 1, tags: []string{"a", "b"}, m: map[string]float64{"x": 0, "y": 1}},
+				record{id: 1, tags: []string{"b", "a"}, m: map[string]float64{"y": 1, "x": math.Copysign(0, -1)}},
+			))
+			t.Run("Differ", testDiffer128(opts,
+				record{},
+				record{id: 1},
+				record{name: "a"},
+				record{tags: []string{"a"}},
+				record{m: map[string]float64{"a": 1}},
+				record{m: map[string]float64{"a": 2}},
+				record{next: &record{}},
+				record{next: &record{id: 1}},
+			))
+		})
+	}
+
+	t.Run("Lanes", func(t *testing.T) {
+		h, err := New[int](0)
+		if err != nil {
+			t.Fatalf("expected nil err, got %s", err)
+		}
+
+		diff := 0
+		for i := 0; i < 1000; i++ {
+			hv := h.GetHash128(i)
+			diff += bits.OnesCount64(hv.Hi ^ hv.Lo)
+		}
+		if diff < 1000*24 || diff > 1000*40 {
+			t.Fatalf("lanes differ in %d bits of %d", diff, 1000*64)
+		}
+	})
+
+	t.Run("Golden", func(t *testing.T) {
+		h, err := New[testPortable](1, WithPortable())
+		if err != nil {
+			t.Fatalf("expected nil err, got %s", err)
+		}
+
+		v := testPortable{I: -1, S: "golden", Is: []int{1, 2}, M: map[string]uint{"a": 1}}
+		want := Hash128{Hi: 0x85c9652c15ae3fe4, Lo: 0xa76a0c10d5029176}
+		if got := h.GetHash128(v); got != want {
+			t.Fatalf("got %#x, want %#x", got, want)
+		}
+	})
+}
+
+func testEqual128[T any](opts []Option, vs ...T) func(t *testing.T) {
+	return func(t *testing.T) {
+		h, err := New[T](0, opts...)
+		if err != nil {
+			t.Fatalf("expected nil err, got %s", err)
+		}
+
+		want := h.GetHash128(vs[0])
+		for _, v := range vs[1:] {
+			if got := h.GetHash128(v); got != want {
+				t.Fatalf("got %#x, want %#x", got, want)
+			}
+		}
+	}
+}
+
+func testDiffer128[T any](opts []Option, vs ...T) func(t *testing.T) {
+	return func(t *testing.T) {
+		h, err := New[T](0, opts...)
+		if err != nil {
+			t.Fatalf("expected nil err, got %s", err)
+		}
+
+		seen := map[Hash128]int{}
+		for i, v := range vs {
+			hv := h.GetHash128(v)
+			if j, ok := seen[hv]; ok {
+				t.Fatalf("values %d and %d have the same hash %#x", j, i, hv)
+			}
+			seen[hv] = i
+		}
+	}
+}
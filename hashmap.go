@@ -0,0 +1,119 @@
+package anyhash
+
+// HashMap is a hash map with keys of any type hashable by AnyHasher, such as
+// slices, maps and structs containing them. Keys are compared by the plan of
+// the hasher: keys with equal contents are the same key, whatever their
+// addresses.
+//
+// Keys must not be modified while they are in the map. A HashMap is not safe
+// for concurrent use.
+type HashMap[K, V any] struct {
+	h       *AnyHasher[K]
+	buckets []*hashEntry[K, V]
+	len     int
+}
+
+type hashEntry[K, V any] struct {
+	hash  uint64
+	key   K
+	value V
+	next  *hashEntry[K, V]
+}
+
+// minBuckets is the number of buckets of an empty map. The number of buckets
+// is always a power of two.
+const minBuckets = 8
+
+// NewHashMap returns an empty map hashing keys with h.
+func NewHashMap[K, V any](h *AnyHasher[K]) *HashMap[K, V] {
+	return &HashMap[K, V]{
+		h:       h,
+		buckets: make([]*hashEntry[K, V], minBuckets),
+	}
+}
+
+// Len returns the number of keys in the map.
+func (m *HashMap[K, V]) Len() int {
+	return m.len
+}
+
+// Get returns the value of k and whether k is in the map.
+func (m *HashMap[K, V]) Get(k K) (V, bool) {
+	if e := m.find(m.h.GetHash64(k), k); e != nil {
+		return e.value, true
+	}
+	var zero V
+	return zero, false
+}
+
+// Set sets the value of k to v.
+func (m *HashMap[K, V]) Set(k K, v V) {
+	hash := m.h.GetHash64(k)
+	if e := m.find(hash, k); e != nil {
+		e.value = v
+		return
+	}
+
+	// Like Go maps, the map grows when it holds more keys than buckets and
+	// never shrinks.
+	if m.len >= len(m.buckets) {
+		m.grow()
+	}
+	i := m.bucket(hash)
+	m.buckets[i] = &hashEntry[K, V]{hash: hash, key: k, value: v, next: m.buckets[i]}
+	m.len++
+}
+
+// Delete removes k from the map and reports whether it was there.
+func (m *HashMap[K, V]) Delete(k K) bool {
+	hash := m.h.GetHash64(k)
+	for ep := &m.buckets[m.bucket(hash)]; *ep != nil; ep = &(*ep).next {
+		if e := *ep; e.hash == hash && m.h.Equal(e.key, k) {
+			*ep = e.next
+			m.len--
+			return true
+		}
+	}
+	return false
+}
+
+// Range calls f for each key and value in the map until f returns false. The
+// order is unspecified, and f must not modify the map.
+func (m *HashMap[K, V]) Range(f func(k K, v V) bool) {
+	for _, e := range m.buckets {
+		for ; e != nil; e = e.next {
+			if !f(e.key, e.value) {
+				return
+			}
+		}
+	}
+}
+
+func (m *HashMap[K, V]) find(hash uint64, k K) *hashEntry[K, V] {
+	for e := m.buckets[m.bucket(hash)]; e != nil; e = e.next {
+		if e.hash == hash && m.h.Equal(e.key, k) {
+			return e
+		}
+	}
+	return nil
+}
+
+func (m *HashMap[K, V]) bucket(hash uint64) int {
+	return int(hash & uint64(len(m.buckets)-1))
+}
+
+// grow doubles the number of buckets, moving the entries without hashing
+// their keys again.
+func (m *HashMap[K, V]) grow() {
+	old := m.buckets
+	m.buckets = make([]*hashEntry[K, V], 2*len(old))
+	for _, e := range old {
+		for e != nil {
+			next := e.next
+			i := m.bucket(e.hash)
+			e.next = m.buckets[i]
+			m.buckets[i] = e
+			e = next
+		}
+	}
+}
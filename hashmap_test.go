@@ -0,0 +1,336 @@
+package anyhash
+
+import (
+	"encoding/binary"
+	"math"
+	"strconv"
+	"testing"
+)
+
+// constAlgorithm hashes everything to zero, so that all keys collide.
+type constAlgorithm struct{}
+
+func (constAlgorithm) Hash(b []byte, seed uint64) uint64 { return 0 }
+
+type testMapKey struct {
+	Name  string
+	Tags  []string
+	Attrs map[string]int
+	F     float64
+	Next  *testMapKey
+}
+
+func testMapKeys(n int) []testMapKey {
+	keys := make([]testMapKey, n)
+	for i := range keys {
+		keys[i] = testMapKey{
+			Name:  "key" + strconv.Itoa(i),
+			Tags:  []string{strconv.Itoa(i % 7), strconv.Itoa(i % 3)},
+			Attrs: map[string]int{"a": i, "b": i * 2},
+			F:     float64(i),
+		}
+		if i%2 == 0 {
+			keys[i].Next = &testMapKey{Name: "next", F: float64(i)}
+		}
+	}
+	return keys
+}
+
+// cloneMapKey returns a key equal to k that shares no memory with it.
+func cloneMapKey(k testMapKey) testMapKey {
+	c := k
+	if k.Tags != nil {
+		c.Tags = append([]string{}, k.Tags...)
+	}
+	if k.Attrs != nil {
+		c.Attrs = map[string]int{}
+		for name, v := range k.Attrs {
+			c.Attrs[name] = v
+		}
+	}
+	if k.Next != nil {
+		next := cloneMapKey(*k.Next)
+		c.Next = &next
+	}
+	return c
+}
+
+func TestHashMap(t *testing.T) {
+	hashers := map[string][]Option{
+		"Default":   nil,
+		"Framed":    {WithFraming()},
+		"Collision": {WithAlgorithm(constAlgorithm{})},
+	}
+
+	for name, opts := range hashers {
+		h, err := New[testMapKey](1, opts...)
+		if err != nil {
+			t.Fatalf("expected nil err, got %s", err)
+		}
+		n := 1000
+		if name == "Collision" {
+			n = 100
+		}
+		keys := testMapKeys(n)
+
+		t.Run(name, func(t *testing.T) {
+			m := NewHashMap[testMapKey, int](h)
+			for i, k := range keys {
+				m.Set(k, i)
+			}
+			if m.Len() != len(keys) {
+				t.Fatalf("got len %d, want %d", m.Len(), len(keys))
+			}
+
+			for i, k := range keys {
+				if v, ok := m.Get(cloneMapKey(k)); !ok || v != i {
+					t.Fatalf("key %d: got %d, %t, want %d, true", i, v, ok, i)
+				}
+			}
+
+			m.Set(cloneMapKey(keys[0]), -1)
+			if v, _ := m.Get(keys[0]); v != -1 || m.Len() != len(keys) {
+				t.Fatalf("got value %d, len %d after overwrite, want -1, %d", v, m.Len(), len(keys))
+			}
+
+			missing := cloneMapKey(keys[1])
+			missing.Attrs["b"]++
+			if _, ok := m.Get(missing); ok {
+				t.Fatal("found missing key")
+			}
+
+			for i := 0; i < len(keys); i += 2 {
+				if !m.Delete(cloneMapKey(keys[i])) {
+					t.Fatalf("key %d: not deleted", i)
+				}
+			}
+			if m.Delete(keys[0]) {
+				t.Fatal("deleted key twice")
+			}
+			for i, k := range keys {
+				if _, ok := m.Get(k); ok != (i%2 == 1) {
+					t.Fatalf("key %d: got %t, want %t", i, ok, i%2 == 1)
+				}
+			}
+
+			seen := 0
+			m.Range(func(k testMapKey, v int) bool {
+				if v%2 != 1 || k.Name != keys[v].Name {
+					t.Fatalf("got entry %q: %d", k.Name, v)
+				}
+				seen++
+				return true
+			})
+			if seen != m.Len() || seen != len(keys)/2 {
+				t.Fatalf("ranged over %d keys, len %d, want %d", seen, m.Len(), len(keys)/2)
+			}
+		})
+	}
+}
+
+func TestHashMapKeyEquality(t *testing.T) {
+	t.Run("Floats", func(t *testing.T) {
+		h, err := New[[]float64](0)
+		if err != nil {
+			t.Fatalf("expected nil err, got %s", err)
+		}
+
+		m := NewHashMap[[]float64, string](h)
+		m.Set([]float64{0, math.NaN()}, "zero")
+		if v, ok := m.Get([]float64{math.Copysign(0, -1), math.NaN()}); !ok || v != "zero" {
+			t.Fatalf("got %q, %t, want \"zero\", true", v, ok)
+		}
+	})
+
+	t.Run("NilSlices", func(t *testing.T) {
+		for _, framed := range []bool{false, true} {
+			var opts []Option
+			if framed {
+				opts = append(opts, WithFraming())
+			}
+			h, err := New[[]int](0, opts...)
+			if err != nil {
+				t.Fatalf("expected nil err, got %s", err)
+			}
+
+			m := NewHashMap[[]int, int](h)
+			m.Set(nil, 1)
+			m.Set([]int{}, 2)
+			if want := map[bool]int{false: 1, true: 2}[framed]; m.Len() != want {
+				t.Fatalf("framed %t: got len %d, want %d", framed, m.Len(), want)
+			}
+		}
+	})
+
+	t.Run("Interfaces", func(t *testing.T) {
+		h, err := New[any](0)
+		if err != nil {
+			t.Fatalf("expected nil err, got %s", err)
+		}
+
+		m := NewHashMap[any, int](h)
+		m.Set([]int{1, 2}, 1)
+		m.Set([]uint{1, 2}, 2)
+		m.Set(nil, 3)
+		if v, _ := m.Get([]int{1, 2}); v != 1 {
+			t.Fatalf("got %d, want 1", v)
+		}
+		if v, _ := m.Get(nil); v != 3 || m.Len() != 3 {
+			t.Fatalf("got %d, len %d, want 3, 3", v, m.Len())
+		}
+	})
+
+	t.Run("Cycles", func(t *testing.T) {
+		type node struct {
+			V    int
+			Next *node
+		}
+		ring := func(n int) *node {
+			first := &node{V: 1}
+			last := first
+			for i := 1; i < n; i++ {
+				last.Next = &node{V: 1}
+				last = last.Next
+			}
+			last.Next = first
+			return first
+		}
+
+		h, err := New[*node](0, WithFraming())
+		if err != nil {
+			t.Fatalf("expected nil err, got %s", err)
+		}
+
+		m := NewHashMap[*node, int](h)
+		m.Set(ring(2), 2)
+		m.Set(ring(3), 3)
+		if v, ok := m.Get(ring(3)); !ok || v != 3 || m.Len() != 2 {
+			t.Fatalf("got %d, %t, len %d, want 3, true, 2", v, ok, m.Len())
+		}
+	})
+}
+
+// serializeMapKey encodes k unambiguously, the way keys of a Go map have to be
+// built without HashMap.
+func serializeMapKey(b []byte, k testMapKey) []byte {
+	appendUint := func(b []byte, v uint64) []byte {
+		var buf [binary.MaxVarintLen64]byte
+		return append(b, buf[:binary.PutUvarint(buf[:], v)]...)
+	}
+	appendString := func(b []byte, s string) []byte {
+		b = appendUint(b, uint64(len(s)))
+		return append(b, s...)
+	}
+
+	b = appendString(b, k.Name)
+	b = appendUint(b, uint64(len(k.Tags)))
+	for _, tag := range k.Tags {
+		b = appendString(b, tag)
+	}
+	// Sorting is skipped: the keys have two attributes, appended in order.
+	b = appendUint(b, uint64(len(k.Attrs)))
+	for _, name := range []string{"a", "b"} {
+		b = appendString(b, name)
+		b = appendUint(b, uint64(k.Attrs[name]))
+	}
+	b = appendUint(b, math.Float64bits(k.F))
+	if k.Next != nil {
+		b = append(b, 1)
+		b = serializeMapKey(b, *k.Next)
+	} else {
+		b = append(b, 0)
+	}
+	return b
+}
+
+func BenchmarkHashMap(b *testing.B) {
+	keys := testMapKeys(1000)
+	h, err := New[testMapKey](0)
+	if err != nil {
+		b.Fatalf("expected nil err, got %s", err)
+	}
+
+	b.Run("HashMap", func(b *testing.B) {
+		m := NewHashMap[testMapKey, int](h)
+		for i, k := range keys {
+			m.Set(k, i)
+		}
+
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, ok := m.Get(keys[i%len(keys)]); !ok {
+				b.Fatal("key not found")
+			}
+		}
+	})
+
+	b.Run("SerializedKeys", func(b *testing.B) {
+		m := map[string]int{}
+		for i, k := range keys {
+			m[string(serializeMapKey(nil, k))] = i
+		}
+
+		var buf []byte
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			buf = serializeMapKey(buf[:0], keys[i%len(keys)])
+			if _, ok := m[string(buf)]; !ok {
+				b.Fatal("key not found")
+			}
+		}
+	})
+}
+
+func BenchmarkHashMapSliceKeys(b *testing.B) {
+	keys := make([][]string, 1000)
+	for i := range keys {
+		keys[i] = []string{"user", strconv.Itoa(i), strconv.Itoa(i * 7)}
+	}
+	h, err := New[[]string](0)
+	if err != nil {
+		b.Fatalf("expected nil err, got %s", err)
+	}
+
+	serialize := func(buf []byte, k []string) []byte {
+		for _, s := range k {
+			var n [binary.MaxVarintLen64]byte
+			buf = append(buf, n[:binary.PutUvarint(n[:], uint64(len(s)))]...)
+			buf = append(buf, s...)
+		}
+		return buf
+	}
+
+	b.Run("HashMap", func(b *testing.B) {
+		m := NewHashMap[[]string, int](h)
+		for i, k := range keys {
+			m.Set(k, i)
+		}
+
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, ok := m.Get(keys[i%len(keys)]); !ok {
+				b.Fatal("key not found")
+			}
+		}
+	})
+
+	b.Run("SerializedKeys", func(b *testing.B) {
+		m := map[string]int{}
+		for i, k := range keys {
+			m[string(serialize(nil, k))] = i
+		}
+
+		var buf []byte
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			buf = serialize(buf[:0], keys[i%len(keys)])
+			if _, ok := m[string(buf)]; !ok {
+				b.Fatal("key not found")
+			}
+		}
+	})
+}
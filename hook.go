@@ -0,0 +1,62 @@
+package anyhash
+
+import (
+	"bytes"
+	"reflect"
+	"unsafe"
+)
+
+// HashAppender is implemented by types that hash as their canonical bytes
+// instead of their fields. It is used on any nesting level, with either a
+// value or a pointer receiver.
+//
+// As with json.Marshaler, a method promoted from an embedded field counts: a
+// struct embedding a HashAppender hashes as the embedded value alone, and its
+// other fields are ignored. Name the field or declare AppendHash on the struct
+// to hash the struct as a whole.
+type HashAppender interface {
+	// AppendHash appends the canonical bytes of the value to b. Values that
+	// must hash equally have to append equal bytes.
+	AppendHash(b []byte) []byte
+}
+
+var hashAppenderType = reflect.TypeOf((*HashAppender)(nil)).Elem()
+
+// hasHashHook reports whether values of typ hash through HashAppender. The
+// method set of *typ includes the methods of typ, so both receivers are found.
+func hasHashHook(typ reflect.Type) bool {
+	switch typ.Kind() {
+	case reflect.Interface, reflect.Pointer:
+		return false
+	}
+	return reflect.PointerTo(typ).Implements(hashAppenderType)
+}
+
+type hookGetter struct {
+	offset   uintptr
+	ptrDepth int
+	typ      reflect.Type
+}
+
+func (h *hookGetter) writeTo(w *writer, p unsafe.Pointer) {
+	np := deref(w, p, h.offset, h.ptrDepth)
+	if np == nil {
+		return
+	}
+
+	var buf [64]byte
+	b := reflect.NewAt(h.typ, np).Interface().(HashAppender).AppendHash(buf[:0])
+	w.writeFrame(uint64(len(b)) + 1)
+	w.writeBytes(b)
+}
+
+func (h *hookGetter) equal(e *equaler, a, b unsafe.Pointer) bool {
+	na, nb, equal, done := derefEqual(a, b, h.offset, h.ptrDepth)
+	if done {
+		return equal
+	}
+
+	ba := reflect.NewAt(h.typ, na).Interface().(HashAppender).AppendHash(nil)
+	bb := reflect.NewAt(h.typ, nb).Interface().(HashAppender).AppendHash(nil)
+	return bytes.Equal(ba, bb)
+}
@@ -0,0 +1,117 @@
+package anyhash
+
+import (
+	"testing"
+)
+
+// testFrac hashes as the reduced fraction, so 1/2 and 2/4 are equal.
+type testFrac struct {
+	num, den int64
+}
+
+func (f testFrac) AppendHash(b []byte) []byte {
+	g := gcd(f.num, f.den)
+	return appendInt64(appendInt64(b, f.num/g), f.den/g)
+}
+
+func gcd(a, b int64) int64 {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// testCached keeps a lazily computed field that must not change the hash.
+type testCached struct {
+	n     int64
+	cache *string
+}
+
+func (c *testCached) AppendHash(b []byte) []byte {
+	return appendInt64(b, c.n)
+}
+
+func appendInt64(b []byte, v int64) []byte {
+	for i := 0; i < 8; i++ {
+		b = append(b, byte(v>>(8*i)))
+	}
+	return b
+}
+
+func TestHashAppender(t *testing.T) {
+	s := "cached"
+
+	t.Run("Value", testType(testFrac{2, 4}, appendInt64(appendInt64(nil, 1), 2)))
+	t.Run("Pointer", testType(&testCached{n: 1, cache: &s}, appendInt64(nil, 1)))
+
+	for _, opts := range [][]Option{nil, {WithFraming()}} {
+		name := "Unframed"
+		if opts != nil {
+			name = "Framed"
+		}
+		t.Run(name, func(t *testing.T) {
+			t.Run("Struct", testEqual(opts,
+				struct {
+					f testFrac
+					c testCached
+				}{testFrac{1, 2}, testCached{n: 1}},
+				struct {
+					f testFrac
+					c testCached
+				}{testFrac{3, 6}, testCached{n: 1, cache: &s}},
+			))
+			t.Run("Slice", testEqual(opts,
+				[]testFrac{{1, 2}, {1, 3}},
+				[]testFrac{{2, 4}, {3, 9}},
+			))
+			t.Run("Map", testEqual(opts,
+				map[string]*testCached{"a": {n: 1}},
+				map[string]*testCached{"a": {n: 1, cache: &s}},
+			))
+			t.Run("Interface", testEqual(opts,
+				[]any{testFrac{1, 2}, &testCached{n: 2}},
+				[]any{testFrac{5, 10}, &testCached{n: 2, cache: &s}},
+			))
+			t.Run("Differ", testDiffer(opts,
+				[]testFrac{{1, 2}},
+				[]testFrac{{1, 3}},
+				[]testFrac{{1, 2}, {1, 2}},
+				nil,
+			))
+			t.Run("NilPointer", testDiffer(opts, nil, &testCached{}))
+
+			type embedded struct {
+				testFrac
+				name string
+			}
+			t.Run("Embedded", testEqual(opts,
+				embedded{testFrac{1, 2}, "a"},
+				embedded{testFrac{2, 4}, "b"},
+			))
+			type named struct {
+				f    testFrac
+				name string
+			}
+			t.Run("Named", testDiffer(opts,
+				named{testFrac{1, 2}, "a"},
+				named{testFrac{1, 2}, "b"},
+			))
+		})
+	}
+}
+
+func testEqual[T any](opts []Option, vs ...T) func(t *testing.T) {
+	return func(t *testing.T) {
+		h, err := New[T](0, opts...)
+		if err != nil {
+			t.Fatalf("expected nil err, got %s", err)
+		}
+
+		want := h.GetHash(vs[0])
+		for _, v := range vs[1:] {
+			if got := h.GetHash(v); got != want {
+				t.Fatalf("got %d, want %d", got, want)
+			}
+		}
+	}
+}
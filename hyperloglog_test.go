@@ -0,0 +1,168 @@
+package anyhash
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHyperLogLog(t *testing.T) {
+	maxCount := 1000000
+	if testing.Short() {
+		maxCount = 100000
+	}
+	keys := testKeys(0, maxCount)
+	h := newTestHasher[testKey](t, WithPortable())
+
+	t.Run("Accuracy", func(t *testing.T) {
+		for _, precision := range []int{10, 14} {
+			s := must(NewHyperLogLog(h, precision))(t)
+			if got := s.Count(); got != 0 {
+				t.Fatalf("precision %d: empty sketch: got %d, want 0", precision, got)
+			}
+
+			// The error must be within 4 standard errors at every count, with
+			// small counts almost exact.
+			stdErr := 1.04 / math.Sqrt(float64(int(1)<<precision))
+			added := 0
+			for _, n := range []int{1, 10, 100, 1000, 10000, 100000, 1000000} {
+				if n > maxCount {
+					break
+				}
+				for ; added < n; added++ {
+					s.Add(keys[added])
+				}
+
+				got := float64(s.Count())
+				if d := math.Abs(got-float64(n)) / float64(n); d > 4*stdErr && math.Abs(got-float64(n)) > 1 {
+					t.Fatalf("precision %d: got %.0f, want %d±%.1f%%", precision, got, n, 400*stdErr)
+				}
+			}
+		}
+	})
+
+	t.Run("Duplicates", func(t *testing.T) {
+		s := must(NewHyperLogLog(h, 12))(t)
+		for _, v := range keys[:5000] {
+			s.Add(v)
+		}
+		want := s.Count()
+		for i := 0; i < 3; i++ {
+			for _, v := range testKeys(0, 5000) {
+				s.Add(v)
+			}
+		}
+		if got := s.Count(); got != want {
+			t.Fatalf("got %d, want %d", got, want)
+		}
+	})
+
+	t.Run("Merge", func(t *testing.T) {
+		n := maxCount / 10
+		all := must(NewHyperLogLog(h, 14))(t)
+		for _, v := range keys[:n] {
+			all.Add(v)
+		}
+
+		// Workers count overlapping parts, and their merged sketch is the
+		// sketch of all the values.
+		merged := must(NewHyperLogLog(h, 14))(t)
+		for w := 0; w < 4; w++ {
+			worker := must(NewHyperLogLog(h, 14))(t)
+			end := (w + 2) * n / 4
+			if end > n {
+				end = n
+			}
+			for _, v := range keys[w*n/4 : end] {
+				worker.Add(v)
+			}
+			if err := merged.Merge(worker); err != nil {
+				t.Fatalf("expected nil err, got %s", err)
+			}
+		}
+		if got, want := merged.Count(), all.Count(); got != want {
+			t.Fatalf("got %d, want %d", got, want)
+		}
+
+		if err := merged.Merge(must(NewHyperLogLog(h, 12))(t)); err == nil {
+			t.Fatal("expected err for different precisions")
+		}
+		seeded, err := New[testKey](1, WithPortable())
+		if err != nil {
+			t.Fatalf("expected nil err, got %s", err)
+		}
+		if err := merged.Merge(must(NewHyperLogLog(seeded, 14))(t)); err == nil {
+			t.Fatal("expected err for different seeds")
+		}
+
+		var keyed [2]*HyperLogLog[testKey]
+		for i := range keyed {
+			h := newTestHasher[testKey](t, WithPortable(), WithAlgorithm(SipHash24([16]byte{byte(i)})))
+			keyed[i] = must(NewHyperLogLog(h, 14))(t)
+		}
+		if err := keyed[0].Merge(keyed[1]); err == nil {
+			t.Fatal("expected err for different keys")
+		}
+	})
+
+	t.Run("Binary", func(t *testing.T) {
+		s := must(NewHyperLogLog(h, 12))(t)
+		for _, v := range keys[:10000] {
+			s.Add(v)
+		}
+		data, err := s.MarshalBinary()
+		if err != nil {
+			t.Fatalf("expected nil err, got %s", err)
+		}
+
+		got, err := UnmarshalHyperLogLog(h, data)
+		if err != nil {
+			t.Fatalf("expected nil err, got %s", err)
+		}
+		if got.Precision() != 12 || got.Count() != s.Count() {
+			t.Fatalf("got precision %d and count %d, want 12 and %d", got.Precision(), got.Count(), s.Count())
+		}
+
+		badPrecision := append([]byte{}, data...)
+		badPrecision[binaryHeaderSize] = 30
+		badRegister := append([]byte{}, data...)
+		badRegister[binaryHeaderSize+1] = 64
+		testUnmarshalErrors(t, data, UnmarshalHyperLogLog[testKey], map[string]testBinaryCase{
+			"Precision": {badPrecision, "precision"},
+			"Register":  {badRegister, "invalid"},
+		})
+	})
+
+	t.Run("Options", func(t *testing.T) {
+		for _, precision := range []int{MinHyperLogLogPrecision - 1, MaxHyperLogLogPrecision + 1} {
+			if _, err := NewHyperLogLog(h, precision); err == nil {
+				t.Fatalf("expected err for precision %d", precision)
+			}
+		}
+
+		// Native hashes are 32-bit on 32-bit platforms.
+		if _, err := NewHyperLogLog(newTestHasher[testKey](t), 14); (err == nil) != is64Bit {
+			t.Fatalf("got err %v on a platform with 64-bit ints %t", err, is64Bit)
+		}
+		if _, err := NewHyperLogLog(newTestHasher[testKey](t, WithAlgorithm(XXHash64)), 14); err != nil {
+			t.Fatalf("expected nil err, got %s", err)
+		}
+	})
+}
+
+func BenchmarkHyperLogLog(b *testing.B) {
+	s, _ := NewHyperLogLog(newTestHasher[testKey](b, WithPortable()), 14)
+	keys := testKeys(0, 1024)
+
+	b.Run("Add", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			s.Add(keys[i%len(keys)])
+		}
+	})
+
+	b.Run("Count", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			s.Count()
+		}
+	})
+}
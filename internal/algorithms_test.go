@@ -0,0 +1,49 @@
+package internal
+
+import (
+	"testing"
+	"unsafe"
+)
+
+// Golden hashes of the reference FNV-1a, xxHash64 and SipHash-2-4
+// implementations, checked on every platform.
+func TestAlgorithmsGolden(t *testing.T) {
+	b := make([]byte, 100)
+	for i := range b {
+		b[i] = byte(i * 7)
+	}
+	const k0, k1 = 0x0706050403020100, 0x0f0e0d0c0b0a0908
+
+	for _, tt := range []struct {
+		n                      int
+		fnv, xx, xxSeeded, sip uint64
+	}{
+		{0, 0xcbf29ce484222325, 0xef46db3751d8e999, 0x98b1582b0977e704, 0x726fdb47dd0e0e31},
+		{1, 0xaf63bd4c8601b7df, 0xe934a84adb052768, 0x83a7b47f8d92d727, 0x74f839c593dc67fd},
+		{3, 0xd93546186bfaf632, 0x9ff70a635a6209ab, 0xc1aa8c800b742639, 0xbac781b6610add0b},
+		{4, 0x107c427f7b704445, 0xae5acdc00a55ac41, 0x1f332230b2638cb9, 0x10646f7bd1e6bfae},
+		{7, 0x2311f5ffe7b81306, 0xd734a6b26f3da63e, 0xa3d9dadcb39a4078, 0xc72fff2256fc020},
+		{8, 0x4f9838d6bdc8a675, 0x87116b3365b924eb, 0xc6631fda6194569, 0x5ebc0d8fca2c614f},
+		{9, 0x84ee1e47bf294d7, 0x340667a92c4324ff, 0xdc89b61808e4fa21, 0x961f5715eec49ae1},
+		{31, 0x21bd134b61b6e9e, 0xf187c62b1e722b7, 0xce9ce149817ce82a, 0xaa8d38c254dad097},
+		{32, 0xb0b2c391709c62a5, 0x91b0cb0931a8c629, 0xa058da75ecc0b760, 0x6c1485606ed8419},
+		{33, 0xdc24952259bafb3f, 0x931b043cf8d65b94, 0xffc2737124d4c73e, 0xb01101e8151d5476},
+		{63, 0xd52d233dfbf7ba7e, 0x219110bf13e2fa26, 0x6735c3eefaeee4d7, 0xa99010387f74e85a},
+		{64, 0x336da95325f26025, 0xbf3052e3445775d0, 0x2e4378846f9f5b8b, 0xc7b60c5195018cb9},
+		{100, 0x77e19fa6f683945, 0x8e2272c08247d5db, 0x9bb39a008c03147c, 0x76df33e87d6b3d22},
+	} {
+		p, n := unsafe.Pointer(&b[0]), uintptr(tt.n)
+		if got := FNV1a64(p, 0, n); got != tt.fnv {
+			t.Errorf("FNV1a64 len %d: got %#x, want %#x", tt.n, got, tt.fnv)
+		}
+		if got := XXHash64(p, 0, n); got != tt.xx {
+			t.Errorf("XXHash64 len %d: got %#x, want %#x", tt.n, got, tt.xx)
+		}
+		if got := XXHash64(p, 42, n); got != tt.xxSeeded {
+			t.Errorf("XXHash64 seed 42 len %d: got %#x, want %#x", tt.n, got, tt.xxSeeded)
+		}
+		if got := SipHash24(p, k0, k1, n); got != tt.sip {
+			t.Errorf("SipHash24 len %d: got %#x, want %#x", tt.n, got, tt.sip)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package internal
+
+import "unsafe"
+
+// Digest64 computes Hash64 of bytes written in parts. It holds back up to 48
+// bytes, since the last block of Hash64 is only known at the end, and the 16
+// bytes before them, which the tail of Hash64 may read again.
+type Digest64 struct {
+	seed, seed1, seed2 uint64
+	n                  uint64
+
+	// buf holds the last 16 processed bytes and then pending ones.
+	buf     [16 + 48]byte
+	pending int
+}
+
+// Reset starts d over with seed.
+func (d *Digest64) Reset(seed uint64) {
+	seed ^= m1
+	*d = Digest64{seed: seed, seed1: seed, seed2: seed}
+}
+
+func (d *Digest64) Write(b []byte) {
+	d.n += uint64(len(b))
+	for len(b) > 0 {
+		if d.pending == 48 {
+			d.block(unsafe.Pointer(&d.buf[16]))
+			copy(d.buf[:16], d.buf[48:])
+			d.pending = 0
+		}
+		if d.pending == 0 && len(b) > 48 {
+			i := 0
+			for ; len(b)-i > 48; i += 48 {
+				d.block(unsafe.Pointer(&b[i]))
+			}
+			copy(d.buf[:16], b[i-16:i])
+			b = b[i:]
+		}
+		c := copy(d.buf[16+d.pending:], b)
+		d.pending += c
+		b = b[c:]
+	}
+}
+
+func (d *Digest64) block(p unsafe.Pointer) {
+	d.seed = mix64(le8(p)^m2, le8(unsafe.Add(p, 8))^d.seed)
+	d.seed1 = mix64(le8(unsafe.Add(p, 16))^m3, le8(unsafe.Add(p, 24))^d.seed1)
+	d.seed2 = mix64(le8(unsafe.Add(p, 32))^m4, le8(unsafe.Add(p, 40))^d.seed2)
+}
+
+// Sum64 returns Hash64 of the bytes written so far without changing d.
+func (d *Digest64) Sum64() uint64 {
+	p := unsafe.Pointer(&d.buf[16])
+	if d.n <= 48 {
+		return Hash64(p, d.seed^m1, uintptr(d.n))
+	}
+
+	seed := d.seed ^ d.seed1 ^ d.seed2
+	l := uintptr(d.pending)
+	for ; l > 16; l -= 16 {
+		seed = mix64(le8(p)^m2, le8(unsafe.Add(p, 8))^seed)
+		p = unsafe.Add(p, 16)
+	}
+	a := le8(unsafe.Add(p, l-16))
+	b := le8(unsafe.Add(p, l-8))
+	return mix64(m5^d.n, mix64(a^m2, b^seed))
+}
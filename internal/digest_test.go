@@ -0,0 +1,52 @@
+package internal
+
+import (
+	"math/rand"
+	"testing"
+	"unsafe"
+)
+
+func TestDigest64(t *testing.T) {
+	r := rand.New(rand.NewSource(1234))
+	b := make([]byte, 300)
+	randBytes(r, b)
+
+	for n := 0; n <= len(b); n++ {
+		seed := r.Uint64()
+		var want uint64
+		if n > 0 {
+			want = Hash64(unsafe.Pointer(&b[0]), seed, uintptr(n))
+		} else {
+			want = Hash64(nil, seed, 0)
+		}
+
+		for _, chunk := range []int{1, 7, 16, 48, 49, 100, n + 1} {
+			var d Digest64
+			d.Reset(seed)
+			for i := 0; i < n; i += chunk {
+				j := i + chunk
+				if j > n {
+					j = n
+				}
+				d.Write(b[i:j])
+			}
+			if got := d.Sum64(); got != want {
+				t.Fatalf("len %d in chunks of %d: got %#x, want %#x", n, chunk, got, want)
+			}
+		}
+
+		var d Digest64
+		d.Reset(seed)
+		for i := 0; i < n; {
+			j := i + r.Intn(120)
+			if j > n {
+				j = n
+			}
+			d.Write(b[i:j])
+			if got, want := d.Sum64(), Hash64(unsafe.Pointer(&b[0]), seed, uintptr(j)); got != want {
+				t.Fatalf("prefix %d of len %d: got %#x, want %#x", j, n, got, want)
+			}
+			i = j
+		}
+	}
+}
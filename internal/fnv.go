@@ -0,0 +1,19 @@
+package internal
+
+import "unsafe"
+
+const (
+	fnvOffset64 = 14695981039346656037
+	fnvPrime64  = 1099511628211
+)
+
+// FNV1a64 is the 64-bit FNV-1a of s bytes at p, starting from the offset
+// basis xored with seed.
+func FNV1a64(p unsafe.Pointer, seed uint64, s uintptr) uint64 {
+	h := fnvOffset64 ^ seed
+	for i := uintptr(0); i < s; i++ {
+		h ^= uint64(*(*byte)(unsafe.Add(p, i)))
+		h *= fnvPrime64
+	}
+	return h
+}
@@ -0,0 +1,535 @@
+// Code generated by anyhashgen. DO NOT EDIT.
+
+package gentest
+
+import (
+	"github.com/hikitani/anyhash"
+)
+
+// HashRecord writes *v to w, hashing it like AnyHasher[Record] made WithPortable.
+func HashRecord(w *anyhash.Writer, v *Record) {
+	anyhashWriteRecord(w, v)
+}
+
+// HashNode writes *v to w, hashing it like AnyHasher[Node] made WithPortable.
+func HashNode(w *anyhash.Writer, v *Node) {
+	anyhashWriteNode(w, v)
+}
+
+// HashMatrix writes *v to w, hashing it like AnyHasher[Matrix] made WithPortable.
+func HashMatrix(w *anyhash.Writer, v *Matrix) {
+	if (*v) == nil {
+		w.WriteFrame(0)
+	} else {
+		w.WriteFrame(uint64(len((*v))) + 1)
+	}
+	for i1 := range *v {
+		if (*v)[i1] == nil {
+			w.WriteFrame(0)
+		} else {
+			w.WriteFrame(uint64(len((*v)[i1])) + 1)
+		}
+		{
+			var buf2 [64]byte
+			b3 := buf2[:0]
+			for i4 := range (*v)[i1] {
+				b3 = w.AppendFloat64(b3, float64((*v)[i1][i4]))
+			}
+			w.WriteBytes(b3)
+		}
+	}
+}
+
+// hashIndex writes *v to w, hashing it like AnyHasher[index] made WithPortable.
+func hashIndex(w *anyhash.Writer, v *index) {
+	if (*v) == nil {
+		w.WriteFrame(0)
+	} else {
+		w.WriteFrame(uint64(len((*v))) + 1)
+	}
+	var sum1 uint64
+	for k2, v3 := range *v {
+		k2, v3 := k2, v3
+		sub4 := w.Sub()
+		{
+			w := &sub4
+			w.WriteString(string(k2))
+			if v3 == nil {
+				w.WriteFrame(0)
+			} else {
+				w.WriteFrame(uint64(len(v3)) + 1)
+			}
+			for i5 := range v3 {
+				if p6 := v3[i5]; p6 == nil {
+					w.WriteNil(0)
+				} else {
+					w.WriteFrame(1)
+					if w.Enter(p6) {
+						anyhashWriteNode(w, p6)
+						w.Leave(p6)
+					}
+				}
+			}
+		}
+		sum1 = w.AddSub(sum1, &sub4)
+	}
+	w.WriteSum(sum1)
+}
+
+func anyhashWriteRecord(w *anyhash.Writer, p *Record) {
+	w.WriteUint(uint64(p.ID), 8)
+	w.WriteUint(uint64(p.Small), 1)
+	w.WriteUint(uint64(p.U16), 2)
+	w.WriteBool(bool(p.Flag))
+	w.WriteFloat64(float64(p.Ratio))
+	w.WriteFloat32(float32(p.Ratio32))
+	w.WriteComplex128(complex128(p.Z))
+	w.WriteComplex64(complex64(p.Z64))
+	w.WriteString(string(p.Name))
+	w.WriteString(string(p.Label))
+	w.WriteUint(uint64(p.Timeout), 8)
+	w.WriteUint(uint64(p.Month), 8)
+	anyhashWritePoint(w, &p.Point)
+	if p.Points == nil {
+		w.WriteFrame(0)
+	} else {
+		w.WriteFrame(uint64(len(p.Points)) + 1)
+	}
+	{
+		var buf1 [64]byte
+		b2 := buf1[:0]
+		for i3 := range p.Points {
+			b2 = w.AppendUint(b2, uint64(p.Points[i3].X), 1)
+			b2 = w.AppendFloat64(b2, float64(p.Points[i3].Y))
+			b2 = w.AppendUint(b2, uint64(p.Points[i3].Z), 2)
+		}
+		w.WriteBytes(b2)
+	}
+	{
+		var buf4 [64]byte
+		b5 := buf4[:0]
+		for i6 := range p.Grid {
+			for i7 := range p.Grid[i6] {
+				b5 = w.AppendUint(b5, uint64(p.Grid[i6][i7]), 2)
+			}
+		}
+		w.WriteBytes(b5)
+	}
+	if p.Floats == nil {
+		w.WriteFrame(0)
+	} else {
+		w.WriteFrame(uint64(len(p.Floats)) + 1)
+	}
+	{
+		var buf8 [64]byte
+		b9 := buf8[:0]
+		for i10 := range p.Floats {
+			b9 = w.AppendFloat64(b9, float64(p.Floats[i10]))
+		}
+		w.WriteBytes(b9)
+	}
+	if p.Names == nil {
+		w.WriteFrame(0)
+	} else {
+		w.WriteFrame(uint64(len(p.Names)) + 1)
+	}
+	for i11 := range p.Names {
+		w.WriteString(string(p.Names[i11]))
+	}
+	if p.Matrix == nil {
+		w.WriteFrame(0)
+	} else {
+		w.WriteFrame(uint64(len(p.Matrix)) + 1)
+	}
+	for i12 := range p.Matrix {
+		if p.Matrix[i12] == nil {
+			w.WriteFrame(0)
+		} else {
+			w.WriteFrame(uint64(len(p.Matrix[i12])) + 1)
+		}
+		{
+			var buf13 [64]byte
+			b14 := buf13[:0]
+			for i15 := range p.Matrix[i12] {
+				b14 = w.AppendFloat32(b14, float32(p.Matrix[i12][i15]))
+			}
+			w.WriteBytes(b14)
+		}
+	}
+	if p16 := p.Ptr; p16 == nil {
+		w.WriteNil(0)
+	} else {
+		w.WriteFrame(1)
+		w.WriteUint(uint64((*p16)), 8)
+	}
+	if p17 := p.PtrPtr; p17 == nil {
+		w.WriteNil(0)
+	} else if p18 := *p17; p18 == nil {
+		w.WriteNil(1)
+	} else {
+		w.WriteFrame(2)
+		w.WriteString(string((*p18)))
+	}
+	if p19 := p.Slice; p19 == nil {
+		w.WriteNil(0)
+	} else {
+		w.WriteFrame(1)
+		if (*p19) == nil {
+			w.WriteFrame(0)
+		} else {
+			w.WriteFrame(uint64(len((*p19))) + 1)
+		}
+		{
+			var buf20 [64]byte
+			b21 := buf20[:0]
+			for i22 := range *p19 {
+				b21 = w.AppendUint(b21, uint64((*p19)[i22]), 4)
+			}
+			w.WriteBytes(b21)
+		}
+	}
+	if p23 := p.Child; p23 == nil {
+		w.WriteNil(0)
+	} else {
+		w.WriteFrame(1)
+		if w.Enter(p23) {
+			anyhashWriteRecord(w, p23)
+			w.Leave(p23)
+		}
+	}
+	if p.Children == nil {
+		w.WriteFrame(0)
+	} else {
+		w.WriteFrame(uint64(len(p.Children)) + 1)
+	}
+	for i24 := range p.Children {
+		if p25 := p.Children[i24]; p25 == nil {
+			w.WriteNil(0)
+		} else {
+			w.WriteFrame(1)
+			if w.Enter(p25) {
+				anyhashWriteRecord(w, p25)
+				w.Leave(p25)
+			}
+		}
+	}
+	if p.Attrs == nil {
+		w.WriteFrame(0)
+	} else {
+		w.WriteFrame(uint64(len(p.Attrs)) + 1)
+	}
+	var sum26 uint64
+	for k27, v28 := range p.Attrs {
+		k27, v28 := k27, v28
+		sub29 := w.Sub()
+		{
+			w := &sub29
+			w.WriteString(string(k27))
+			w.WriteUint(uint64(v28), 8)
+		}
+		sum26 = w.AddSub(sum26, &sub29)
+	}
+	w.WriteSum(sum26)
+	if p.ByPoint == nil {
+		w.WriteFrame(0)
+	} else {
+		w.WriteFrame(uint64(len(p.ByPoint)) + 1)
+	}
+	var sum30 uint64
+	for k31, v32 := range p.ByPoint {
+		k31, v32 := k31, v32
+		sub33 := w.Sub()
+		{
+			w := &sub33
+			anyhashWritePoint(w, &k31)
+			if v32 == nil {
+				w.WriteFrame(0)
+			} else {
+				w.WriteFrame(uint64(len(v32)) + 1)
+			}
+			for i34 := range v32 {
+				w.WriteString(string(v32[i34]))
+			}
+		}
+		sum30 = w.AddSub(sum30, &sub33)
+	}
+	w.WriteSum(sum30)
+	if p.Nested == nil {
+		w.WriteFrame(0)
+	} else {
+		w.WriteFrame(uint64(len(p.Nested)) + 1)
+	}
+	var sum35 uint64
+	for k36, v37 := range p.Nested {
+		k36, v37 := k36, v37
+		sub38 := w.Sub()
+		{
+			w := &sub38
+			w.WriteString(string(k36))
+			if v37 == nil {
+				w.WriteFrame(0)
+			} else {
+				w.WriteFrame(uint64(len(v37)) + 1)
+			}
+			var sum39 uint64
+			for k40, v41 := range v37 {
+				k40, v41 := k40, v41
+				sub42 := w.Sub()
+				{
+					w := &sub42
+					w.WriteUint(uint64(k40), 8)
+					w.WriteBool(bool(v41))
+				}
+				sum39 = w.AddSub(sum39, &sub42)
+			}
+			w.WriteSum(sum39)
+		}
+		sum35 = w.AddSub(sum35, &sub38)
+	}
+	w.WriteSum(sum35)
+	{
+		var buf43 [64]byte
+		b44 := (&p.Fold).AppendHash(buf43[:0])
+		w.WriteFrame(uint64(len(b44)) + 1)
+		w.WriteBytes(b44)
+	}
+	if p.Folds == nil {
+		w.WriteFrame(0)
+	} else {
+		w.WriteFrame(uint64(len(p.Folds)) + 1)
+	}
+	for i45 := range p.Folds {
+		{
+			var buf46 [64]byte
+			b47 := (&p.Folds[i45]).AppendHash(buf46[:0])
+			w.WriteFrame(uint64(len(b47)) + 1)
+			w.WriteBytes(b47)
+		}
+	}
+	if p48 := p.Frac; p48 == nil {
+		w.WriteNil(0)
+	} else {
+		w.WriteFrame(1)
+		if w.Enter(p48) {
+			anyhashWriteFrac(w, p48)
+			w.Leave(p48)
+		}
+	}
+	anyhashWriteStruct1(w, &p.Inline)
+	if p.Flats == nil {
+		w.WriteFrame(0)
+	} else {
+		w.WriteFrame(uint64(len(p.Flats)) + 1)
+	}
+	{
+		var buf49 [64]byte
+		b50 := buf49[:0]
+		for i51 := range p.Flats {
+			b50 = w.AppendUint(b50, uint64(p.Flats[i51].P.X), 1)
+			b50 = w.AppendFloat64(b50, float64(p.Flats[i51].P.Y))
+			b50 = w.AppendUint(b50, uint64(p.Flats[i51].P.Z), 2)
+			for i52 := range p.Flats[i51].Ps {
+				b50 = w.AppendUint(b50, uint64(p.Flats[i51].Ps[i52].X), 1)
+				b50 = w.AppendFloat64(b50, float64(p.Flats[i51].Ps[i52].Y))
+				b50 = w.AppendUint(b50, uint64(p.Flats[i51].Ps[i52].Z), 2)
+			}
+			b50 = w.AppendFloat32(b50, real(complex64(p.Flats[i51].C)))
+			b50 = w.AppendFloat32(b50, imag(complex64(p.Flats[i51].C)))
+			b50 = w.AppendBool(b50, bool(p.Flats[i51].B))
+		}
+		w.WriteBytes(b50)
+	}
+	anyhashWriteTagged(w, &p.Tags)
+	w.WriteUint(uint64(p.private), 4)
+}
+
+func anyhashWriteNode(w *anyhash.Writer, p *Node) {
+	w.WriteUint(uint64(p.Value), 8)
+	if p1 := p.Next; p1 == nil {
+		w.WriteNil(0)
+	} else {
+		w.WriteFrame(1)
+		if w.Enter(p1) {
+			anyhashWriteNode(w, p1)
+			w.Leave(p1)
+		}
+	}
+	if p2 := p.Prev; p2 == nil {
+		w.WriteNil(0)
+	} else {
+		w.WriteFrame(1)
+		if w.Enter(p2) {
+			anyhashWriteNode(w, p2)
+			w.Leave(p2)
+		}
+	}
+}
+
+func anyhashWritePoint(w *anyhash.Writer, p *Point) {
+	w.WriteUint(uint64(p.X), 1)
+	w.WriteFloat64(float64(p.Y))
+	w.WriteUint(uint64(p.Z), 2)
+}
+
+func anyhashWriteFrac(w *anyhash.Writer, p *Frac) {
+	{
+		var buf1 [64]byte
+		b2 := p.AppendHash(buf1[:0])
+		w.WriteFrame(uint64(len(b2)) + 1)
+		w.WriteBytes(b2)
+	}
+}
+
+func anyhashWriteStruct1(w *anyhash.Writer, p *struct {
+	A uint8
+	B string
+}) {
+	w.WriteUint(uint64(p.A), 1)
+	w.WriteString(string(p.B))
+}
+
+func anyhashWriteTagged(w *anyhash.Writer, p *Tagged) {
+	if p.Opt == "" {
+		w.WriteFrame(0)
+	} else {
+		w.WriteFrame(1)
+		w.WriteString(string(p.Opt))
+	}
+	if p.OptPtr == nil {
+		w.WriteFrame(0)
+	} else {
+		w.WriteFrame(1)
+		if p1 := p.OptPtr; p1 == nil {
+			w.WriteNil(0)
+		} else {
+			w.WriteFrame(1)
+			if w.Enter(p1) {
+				anyhashWritePoint(w, p1)
+				w.Leave(p1)
+			}
+		}
+	}
+	if p.OptPoint == (Point{}) {
+		w.WriteFrame(0)
+	} else {
+		w.WriteFrame(1)
+		anyhashWritePoint(w, &p.OptPoint)
+	}
+	if p.OptSlice == nil {
+		w.WriteFrame(0)
+	} else {
+		w.WriteFrame(1)
+		if p.OptSlice == nil {
+			w.WriteFrame(0)
+		} else {
+			w.WriteFrame(uint64(len(p.OptSlice)) + 1)
+		}
+		{
+			var buf2 [64]byte
+			b3 := buf2[:0]
+			for i4 := range p.OptSlice {
+				b3 = w.AppendUint(b3, uint64(p.OptSlice[i4]), 8)
+			}
+			w.WriteBytes(b3)
+		}
+	}
+	if func() bool {
+		for i5 := range p.OptArr {
+			if !(p.OptArr[i5] == nil) {
+				return false
+			}
+		}
+		return true
+	}() {
+		w.WriteFrame(0)
+	} else {
+		w.WriteFrame(1)
+		for i6 := range p.OptArr {
+			if p.OptArr[i6] == nil {
+				w.WriteFrame(0)
+			} else {
+				w.WriteFrame(uint64(len(p.OptArr[i6])) + 1)
+			}
+			{
+				var buf7 [64]byte
+				b8 := buf7[:0]
+				for i9 := range p.OptArr[i6] {
+					b8 = w.AppendUint(b8, uint64(p.OptArr[i6][i9]), 8)
+				}
+				w.WriteBytes(b8)
+			}
+		}
+	}
+	if p.OptRec.S == nil {
+		w.WriteFrame(0)
+	} else {
+		w.WriteFrame(1)
+		anyhashWriteStruct2(w, &p.OptRec)
+	}
+	if p.Set == nil {
+		w.WriteFrame(0)
+	} else {
+		w.WriteFrame(uint64(len(p.Set)) + 1)
+	}
+	var sum10 uint64
+	for i11 := range p.Set {
+		sub12 := w.Sub()
+		{
+			w := &sub12
+			w.WriteString(string(p.Set[i11]))
+		}
+		sum10 = w.AddSub(sum10, &sub12)
+	}
+	w.WriteSum(sum10)
+	var sum13 uint64
+	for i14 := range p.Pairs {
+		sub15 := w.Sub()
+		{
+			w := &sub15
+			anyhashWritePoint(w, &p.Pairs[i14])
+		}
+		sum13 = w.AddSub(sum13, &sub15)
+	}
+	w.WriteSum(sum13)
+	if p.OptSet == nil {
+		w.WriteFrame(0)
+	} else {
+		w.WriteFrame(1)
+		if p.OptSet == nil {
+			w.WriteFrame(0)
+		} else {
+			w.WriteFrame(uint64(len(p.OptSet)) + 1)
+		}
+		var sum16 uint64
+		for i17 := range p.OptSet {
+			sub18 := w.Sub()
+			{
+				w := &sub18
+				if p19 := p.OptSet[i17]; p19 == nil {
+					w.WriteNil(0)
+				} else {
+					w.WriteFrame(1)
+					w.WriteUint(uint64((*p19)), 8)
+				}
+			}
+			sum16 = w.AddSub(sum16, &sub18)
+		}
+		w.WriteSum(sum16)
+	}
+}
+
+func anyhashWriteStruct2(w *anyhash.Writer, p *struct{ S []int }) {
+	if p.S == nil {
+		w.WriteFrame(0)
+	} else {
+		w.WriteFrame(uint64(len(p.S)) + 1)
+	}
+	{
+		var buf1 [64]byte
+		b2 := buf1[:0]
+		for i3 := range p.S {
+			b2 = w.AppendUint(b2, uint64(p.S[i3]), 8)
+		}
+		w.WriteBytes(b2)
+	}
+}
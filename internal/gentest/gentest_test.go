@@ -0,0 +1,125 @@
+package gentest
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/hikitani/anyhash"
+)
+
+var testOptions = map[string][]anyhash.Option{
+	"Default":   nil,
+	"Framed":    {anyhash.WithFraming()},
+	"RawFloats": {anyhash.WithRawFloats()},
+	"XXHash64":  {anyhash.WithFraming(), anyhash.WithAlgorithm(anyhash.XXHash64)},
+}
+
+// testGenerated compares the hashes of random values of T written by gen with
+// the ones of AnyHasher with every option.
+func testGenerated[T any](gen func(w *anyhash.Writer, v *T)) func(t *testing.T) {
+	return func(t *testing.T) {
+		n := 2000
+		if testing.Short() {
+			n = 200
+		}
+
+		typ := reflect.TypeOf((*T)(nil)).Elem()
+		for name, opts := range testOptions {
+			h, err := anyhash.New[T](7, append([]anyhash.Option{anyhash.WithPortable()}, opts...)...)
+			if err != nil {
+				t.Fatalf("expected nil err, got %s", err)
+			}
+
+			r := rand.New(rand.NewSource(1))
+			for i := 0; i < n; i++ {
+				v := RandValue(r, typ, 3).Interface().(T)
+				w := anyhash.NewWriter(7, opts...)
+				gen(&w, &v)
+				if got, want := w.Sum64(), h.GetHash64(v); got != want {
+					t.Fatalf("%s: %+v: got %#x, want %#x", name, v, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestGenerated(t *testing.T) {
+	t.Run("Record", testGenerated(HashRecord))
+	t.Run("Node", testGenerated(HashNode))
+	t.Run("Matrix", testGenerated(HashMatrix))
+	t.Run("Index", testGenerated(hashIndex))
+}
+
+func TestGeneratedCycles(t *testing.T) {
+	ring := func(n int) *Node {
+		first := &Node{Value: 1}
+		last := first
+		for i := 1; i < n; i++ {
+			last.Next = &Node{Value: i, Prev: last}
+			last = last.Next
+		}
+		last.Next, first.Prev = first, last
+		return first
+	}
+
+	for name, opts := range testOptions {
+		h, err := anyhash.New[Node](3, append([]anyhash.Option{anyhash.WithPortable()}, opts...)...)
+		if err != nil {
+			t.Fatalf("expected nil err, got %s", err)
+		}
+
+		for n := 1; n < 5; n++ {
+			v := ring(n)
+			w := anyhash.NewWriter(3, opts...)
+			HashNode(&w, v)
+			if got, want := w.Sum64(), h.GetHash64(*v); got != want {
+				t.Fatalf("%s: ring of %d: got %#x, want %#x", name, n, got, want)
+			}
+		}
+
+		rec := &Record{ID: 1}
+		rec.Child = rec
+		rec.Children = []*Record{rec, {Child: rec}}
+		hr, err := anyhash.New[Record](3, append([]anyhash.Option{anyhash.WithPortable()}, opts...)...)
+		if err != nil {
+			t.Fatalf("expected nil err, got %s", err)
+		}
+		w := anyhash.NewWriter(3, opts...)
+		HashRecord(&w, rec)
+		if got, want := w.Sum64(), hr.GetHash64(*rec); got != want {
+			t.Fatalf("%s: record: got %#x, want %#x", name, got, want)
+		}
+	}
+}
+
+func BenchmarkGenerated(b *testing.B) {
+	v := Record{
+		ID:     1,
+		Name:   "record",
+		Points: []Point{{1, 2, 3}, {4, 5, 6}},
+		Names:  []string{"a", "b", "c"},
+		Attrs:  map[string]int{"a": 1, "b": 2},
+		Child:  &Record{ID: 2},
+	}
+	h, err := anyhash.New[Record](0, anyhash.WithPortable())
+	if err != nil {
+		b.Fatalf("expected nil err, got %s", err)
+	}
+
+	b.Run("Generated", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			w := anyhash.NewWriter(0)
+			HashRecord(&w, &v)
+			_ = w.Sum64()
+		}
+	})
+
+	b.Run("AnyHasher", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = h.GetHash64(v)
+		}
+	})
+}
@@ -0,0 +1,69 @@
+package gentest
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"unsafe"
+)
+
+// RandValue returns a random value of typ drawn from a few values per kind, so
+// that nil, empty and equal values are frequent. Pointers, slices, arrays,
+// maps and interfaces are nested at most depth deep. Funcs and chans are left
+// nil.
+func RandValue(r *rand.Rand, typ reflect.Type, depth int) reflect.Value {
+	v := reflect.New(typ).Elem()
+	switch typ.Kind() {
+	case reflect.Bool:
+		v.SetBool(r.Intn(2) == 0)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(int64(r.Intn(3) - 1))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		v.SetUint(uint64(r.Intn(3)))
+	case reflect.Float32, reflect.Float64:
+		floats := []float64{0, math.Copysign(0, -1), 1, math.NaN(), math.Inf(1)}
+		v.SetFloat(floats[r.Intn(len(floats))])
+	case reflect.Complex64, reflect.Complex128:
+		v.SetComplex(complex(float64(r.Intn(2)), math.Copysign(0, float64(r.Intn(2)-1))))
+	case reflect.String:
+		strs := []string{"", "a", "ab", "A"}
+		v.SetString(strs[r.Intn(len(strs))])
+	case reflect.Pointer:
+		if depth > 0 && r.Intn(3) != 0 {
+			p := reflect.New(typ.Elem())
+			p.Elem().Set(RandValue(r, typ.Elem(), depth-1))
+			v.Set(p)
+		}
+	case reflect.Slice:
+		if depth > 0 && r.Intn(4) != 0 {
+			n := r.Intn(3)
+			v.Set(reflect.MakeSlice(typ, n, n))
+			for i := 0; i < n; i++ {
+				v.Index(i).Set(RandValue(r, typ.Elem(), depth-1))
+			}
+		}
+	case reflect.Array:
+		for i := 0; i < typ.Len(); i++ {
+			v.Index(i).Set(RandValue(r, typ.Elem(), depth-1))
+		}
+	case reflect.Map:
+		if depth > 0 && r.Intn(4) != 0 {
+			v.Set(reflect.MakeMap(typ))
+			for i := r.Intn(3); i > 0; i-- {
+				v.SetMapIndex(RandValue(r, typ.Key(), depth-1), RandValue(r, typ.Elem(), depth-1))
+			}
+		}
+	case reflect.Interface:
+		dyn := []reflect.Type{reflect.TypeOf(0), reflect.TypeOf(""), reflect.TypeOf([]float64{}), reflect.TypeOf(Point{})}
+		if depth > 0 && r.Intn(4) != 0 {
+			v.Set(RandValue(r, dyn[r.Intn(len(dyn))], depth-1))
+		}
+	case reflect.Struct:
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			fv := reflect.NewAt(field.Type, unsafe.Pointer(v.Field(i).UnsafeAddr())).Elem()
+			fv.Set(RandValue(r, field.Type, depth))
+		}
+	}
+	return v
+}
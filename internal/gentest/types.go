@@ -0,0 +1,131 @@
+// Package gentest holds types hashed by code generated by anyhashgen, which
+// its tests compare with AnyHasher, and random values for the tests of
+// anyhash.
+package gentest
+
+import (
+	"strings"
+	"time"
+)
+
+//go:generate go run github.com/hikitani/anyhash/cmd/anyhashgen
+
+// Record has a field of almost every kind.
+//
+//anyhash:generate
+type Record struct {
+	ID       int
+	Small    int8
+	U16      uint16
+	Flag     bool
+	Ratio    float64
+	Ratio32  float32
+	Z        complex128
+	Z64      complex64
+	Name     string
+	Label    Label
+	Timeout  time.Duration
+	Month    time.Month
+	Point    Point
+	Points   []Point
+	Grid     [2][3]int16
+	Floats   []float64
+	Names    []string
+	Matrix   [][]float32
+	Ptr      *int
+	PtrPtr   **string
+	Slice    *[]uint32
+	Child    *Record
+	Children []*Record
+	Attrs    map[string]int
+	ByPoint  map[Point][]string
+	Nested   map[string]map[int]bool
+	Fold     Fold
+	Folds    []Fold
+	Frac     *Frac
+	Inline   struct {
+		A uint8
+		B string
+	}
+	Flats   []Flat
+	Tags    Tagged
+	private uint32
+}
+
+// Point is flat: it is hashed as a block of memory with its padding left out.
+type Point struct {
+	X int8
+	Y float64
+	Z uint16
+}
+
+// Flat is flat and nested.
+type Flat struct {
+	P  Point
+	Ps [2]Point
+	C  complex64
+	B  bool
+}
+
+type Label string
+
+// Fold hashes case-insensitively.
+type Fold string
+
+func (f Fold) AppendHash(b []byte) []byte {
+	return append(b, strings.ToLower(string(f))...)
+}
+
+// Frac hashes as the reduced fraction.
+type Frac struct {
+	Num, Den int64
+}
+
+func (f *Frac) AppendHash(b []byte) []byte {
+	a, d := f.Num, f.Den
+	for d != 0 {
+		a, d = d, a%d
+	}
+	if a == 0 {
+		a = 1
+	}
+	num, den := f.Num/a, f.Den/a
+	for i := 0; i < 8; i++ {
+		b = append(b, byte(num>>(8*i)))
+	}
+	for i := 0; i < 8; i++ {
+		b = append(b, byte(den>>(8*i)))
+	}
+	return b
+}
+
+// Tagged has tagged fields.
+type Tagged struct {
+	Skip     func()            `anyhash:"-"`
+	Opt      string            `anyhash:"omitempty"`
+	OptPtr   *Point            `anyhash:"omitempty"`
+	OptPoint Point             `anyhash:"omitempty"`
+	OptSlice []int             `anyhash:"omitempty"`
+	OptArr   [2][]int          `anyhash:"omitempty"`
+	OptRec   struct{ S []int } `anyhash:"omitempty"`
+	Set      []string          `anyhash:"unordered"`
+	Pairs    [3]Point          `anyhash:"unordered"`
+	OptSet   []*int            `anyhash:"omitempty,unordered"`
+}
+
+// Node links into lists and rings.
+//
+//anyhash:generate
+type Node struct {
+	Value int
+	Next  *Node
+	Prev  *Node
+}
+
+// Matrix is marked without being a struct.
+//
+//anyhash:generate
+type Matrix [][]float64
+
+//anyhash:generate
+type index map[string][]*Node
@@ -0,0 +1,240 @@
+package internal
+
+import (
+	"encoding/binary"
+	"math"
+	"math/bits"
+	"math/rand"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+// Smhasher tests of Hash128, see hash_test.go. Every lane is checked on its
+// own as well, so a weak lane is not hidden by the other.
+
+type HashSet128 struct {
+	m      map[[2]uint64]struct{}
+	lo, hi map[uint64]struct{}
+	n      int
+}
+
+func newHashSet128() *HashSet128 {
+	return &HashSet128{
+		m:  make(map[[2]uint64]struct{}),
+		lo: make(map[uint64]struct{}),
+		hi: make(map[uint64]struct{}),
+	}
+}
+
+func (s *HashSet128) add(lo, hi uint64) {
+	s.m[[2]uint64{lo, hi}] = struct{}{}
+	s.lo[lo] = struct{}{}
+	s.hi[hi] = struct{}{}
+	s.n++
+}
+
+func (s *HashSet128) addB(b []byte) {
+	s.addB_seed(b, 0)
+}
+
+func (s *HashSet128) addB_seed(b []byte, seed uint64) {
+	var p unsafe.Pointer
+	if len(b) > 0 {
+		p = unsafe.Pointer(&b[0])
+	}
+	s.add(Hash128(p, seed, seed, uintptr(len(b))))
+}
+
+func (s *HashSet128) check(t *testing.T) {
+	t.Helper()
+	checkCollisions(t, "hash", s.n, len(s.m), 128)
+	checkCollisions(t, "low lane", s.n, len(s.lo), 64)
+	checkCollisions(t, "high lane", s.n, len(s.hi), 64)
+}
+
+func checkCollisions(t *testing.T, name string, n, distinct, size int) {
+	t.Helper()
+	const SLOP = 50.0
+	collisions := n - distinct
+	pairs := int64(n) * int64(n-1) / 2
+	expected := float64(pairs) / math.Pow(2.0, float64(size))
+	stddev := math.Sqrt(expected)
+	if float64(collisions) > expected+SLOP*(3*stddev+1) {
+		t.Errorf("unexpected number of %s collisions: got=%d mean=%f stddev=%f", name, collisions, expected, stddev)
+	}
+}
+
+func TestHash128AppendedZeros(t *testing.T) {
+	s := []byte("hello" + strings.Repeat("\x00", 256))
+	h := newHashSet128()
+	for i := 0; i <= len(s); i++ {
+		h.addB(s[:i])
+	}
+	h.check(t)
+}
+
+func TestHash128SmallKeys(t *testing.T) {
+	h := newHashSet128()
+	var b [3]byte
+	for i := 0; i < 256; i++ {
+		b[0] = byte(i)
+		h.addB(b[:1])
+		for j := 0; j < 256; j++ {
+			b[1] = byte(j)
+			h.addB(b[:2])
+			if !testing.Short() {
+				for k := 0; k < 256; k++ {
+					b[2] = byte(k)
+					h.addB(b[:3])
+				}
+			}
+		}
+	}
+	h.check(t)
+}
+
+func TestHash128Zeros(t *testing.T) {
+	N := 64 * 1024
+	if testing.Short() {
+		N = 1024
+	}
+	h := newHashSet128()
+	b := make([]byte, N)
+	for i := 0; i <= N; i++ {
+		h.addB(b[:i])
+	}
+	h.check(t)
+}
+
+func TestHash128Sparse(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping in short mode")
+	}
+	for _, tt := range []struct{ n, k int }{{32, 6}, {64, 5}, {256, 3}, {2048, 2}} {
+		b := make([]byte, tt.n/8)
+		h := newHashSet128()
+		setbits128(h, b, 0, tt.k)
+		h.check(t)
+	}
+}
+
+func setbits128(h *HashSet128, b []byte, i int, k int) {
+	h.addB(b)
+	if k == 0 {
+		return
+	}
+	for j := i; j < len(b)*8; j++ {
+		b[j/8] |= byte(1 << uint(j&7))
+		setbits128(h, b, j+1, k-1)
+		b[j/8] &= byte(^(1 << uint(j&7)))
+	}
+}
+
+func TestHash128Seed(t *testing.T) {
+	h := newHashSet128()
+	const N = 100000
+	s := []byte("hello")
+	for i := 0; i < N; i++ {
+		h.addB_seed(s, uint64(i))
+	}
+	h.check(t)
+}
+
+// Inputs starting with a multiplier of Hash64 cancel its state for every seed.
+// Both halves of Hash128 must still depend on the rest of such inputs and on
+// the seed, each on its own, and never be equal.
+func TestHash128Multipliers(t *testing.T) {
+	r := rand.New(rand.NewSource(1234))
+	for _, m := range []uint64{m1, m2, m3, m4, m5} {
+		for _, n := range []int{16, 28, 64} {
+			h := newHashSet128()
+			b := make([]byte, n)
+			binary.LittleEndian.PutUint64(b, m)
+			p := unsafe.Pointer(&b[0])
+			for i := 0; i < 1000; i++ {
+				randBytes(r, b[8:])
+				lo, hi := Hash128(p, 0, 0, uintptr(n))
+				if lo == hi {
+					t.Fatalf("len %d starting with %#x: equal halves %#x", n, m, lo)
+				}
+				h.add(lo, hi)
+				h.add(Hash128(p, uint64(i)+1, uint64(i)+1, uintptr(n)))
+			}
+			h.check(t)
+		}
+	}
+}
+
+// Flipping a single bit of a key should flip each of the 128 output bits with
+// 50% probability.
+func TestHash128Avalanche(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping in short mode")
+	}
+	for _, n := range []int{2, 4, 8, 16, 32, 200} {
+		avalanche128(t, make([]byte, n))
+	}
+}
+
+func avalanche128(t *testing.T, b []byte) {
+	const REP = 20000
+	r := rand.New(rand.NewSource(1234))
+	n := len(b) * 8
+	p := unsafe.Pointer(&b[0])
+
+	grid := make([][128]int, n)
+	for z := 0; z < REP; z++ {
+		randBytes(r, b)
+		lo, hi := Hash128(p, 0, 0, uintptr(len(b)))
+		for i := 0; i < n; i++ {
+			b[i/8] ^= 1 << uint(i&7)
+			lo2, hi2 := Hash128(p, 0, 0, uintptr(len(b)))
+			b[i/8] ^= 1 << uint(i&7)
+
+			d := [2]uint64{lo ^ lo2, hi ^ hi2}
+			g := &grid[i]
+			for j := 0; j < 128; j++ {
+				g[j] += int(d[j/64] >> uint(j%64) & 1)
+			}
+		}
+	}
+
+	N := n * 128
+	var c float64
+	for c = 0.0; math.Pow(math.Erf(c/math.Sqrt(2)), float64(N)) < .9999; c += .1 {
+	}
+	c *= 4.0
+	mean := .5 * REP
+	stddev := .5 * math.Sqrt(REP)
+	low := int(mean - c*stddev)
+	high := int(mean + c*stddev)
+	for i := 0; i < n; i++ {
+		for j := 0; j < 128; j++ {
+			if x := grid[i][j]; x < low || x > high {
+				t.Errorf("bad bias for %d byte key bit %d -> bit %d: %d/%d", len(b), i, j, x, REP)
+			}
+		}
+	}
+}
+
+// The lanes must not be related: about half of their bits differ.
+func TestHash128Lanes(t *testing.T) {
+	const N = 100000
+	r := rand.New(rand.NewSource(1234))
+	b := make([]byte, 24)
+	p := unsafe.Pointer(&b[0])
+
+	diff := 0
+	for i := 0; i < N; i++ {
+		randBytes(r, b)
+		lo, hi := Hash128(p, r.Uint64(), 0, uintptr(r.Intn(len(b)+1)))
+		diff += bits.OnesCount64(lo ^ hi)
+	}
+
+	mean := 32.0 * N
+	stddev := math.Sqrt(16.0 * N)
+	if d := float64(diff); math.Abs(d-mean) > 6*stddev {
+		t.Errorf("lanes differ in %f bits on average, want 32", d/N)
+	}
+}
@@ -5,7 +5,7 @@ package internal
 import "unsafe"
 
 func MemhashFallback(p unsafe.Pointer, seed, s uintptr) uintptr {
-	a, b := mix32(uint32(seed^(s>>32)), uint32(s))
+	a, b := mix32(uint32(seed^uintptr(uint64(s)>>32)), uint32(s))
 	if s == 0 {
 		return uintptr(a ^ b)
 	}
@@ -7,14 +7,6 @@ import (
 	"unsafe"
 )
 
-const (
-	m1 = 0xa0761d6478bd642f
-	m2 = 0xe7037ed1a0b428db
-	m3 = 0x8ebc6af09c88c6e3
-	m4 = 0x589965cc75374cc3
-	m5 = 0x1d8e4e27c47d124f
-)
-
 func MemhashFallback(p unsafe.Pointer, seed, s uintptr) uintptr {
 	var a, b uintptr
 	seed ^= m1
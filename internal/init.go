@@ -9,6 +9,10 @@ const is64Bit = uint64(^uintptr(0)) == ^uint64(0)
 
 var endian binary.ByteOrder
 
+// BigEndian reports whether the platform stores words most significant byte
+// first.
+var BigEndian bool
+
 func init() {
 	buf := [2]byte{}
 	*(*uint16)(unsafe.Pointer(&buf[0])) = uint16(0xABCD)
@@ -18,6 +22,7 @@ func init() {
 		endian = binary.LittleEndian
 	case [2]byte{0xAB, 0xCD}:
 		endian = binary.BigEndian
+		BigEndian = true
 	default:
 		panic("Could not determine native endianness.")
 	}
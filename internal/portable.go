@@ -0,0 +1,117 @@
+package internal
+
+import (
+	"encoding/binary"
+	"math/bits"
+	"unsafe"
+)
+
+const (
+	m1 = 0xa0761d6478bd642f
+	m2 = 0xe7037ed1a0b428db
+	m3 = 0x8ebc6af09c88c6e3
+	m4 = 0x589965cc75374cc3
+	m5 = 0x1d8e4e27c47d124f
+)
+
+// Hash64 is the 64-bit MemhashFallback reading words as little endian, so it
+// returns the same hash for the same bytes on every platform. On 64-bit little
+// endian platforms it equals MemhashFallback.
+func Hash64(p unsafe.Pointer, seed uint64, s uintptr) uint64 {
+	var a, b uint64
+	seed ^= m1
+	switch {
+	case s == 0:
+		return seed
+	case s < 4:
+		a = uint64(*(*byte)(p))
+		a |= uint64(*(*byte)(unsafe.Add(p, s>>1))) << 8
+		a |= uint64(*(*byte)(unsafe.Add(p, s-1))) << 16
+	case s == 4:
+		a = le4(p)
+		b = a
+	case s < 8:
+		a = le4(p)
+		b = le4(unsafe.Add(p, s-4))
+	case s == 8:
+		a = le8(p)
+		b = a
+	case s <= 16:
+		a = le8(p)
+		b = le8(unsafe.Add(p, s-8))
+	default:
+		l := s
+		if l > 48 {
+			seed1 := seed
+			seed2 := seed
+			for ; l > 48; l -= 48 {
+				seed = mix64(le8(p)^m2, le8(unsafe.Add(p, 8))^seed)
+				seed1 = mix64(le8(unsafe.Add(p, 16))^m3, le8(unsafe.Add(p, 24))^seed1)
+				seed2 = mix64(le8(unsafe.Add(p, 32))^m4, le8(unsafe.Add(p, 40))^seed2)
+				p = unsafe.Add(p, 48)
+			}
+			seed ^= seed1 ^ seed2
+		}
+		for ; l > 16; l -= 16 {
+			seed = mix64(le8(p)^m2, le8(unsafe.Add(p, 8))^seed)
+			p = unsafe.Add(p, 16)
+		}
+		a = le8(unsafe.Add(p, l-16))
+		b = le8(unsafe.Add(p, l-8))
+	}
+
+	return mix64(m5^uint64(s), mix64(a^m2, b^seed))
+}
+
+func mix64(a, b uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	return hi ^ lo
+}
+
+func le4(p unsafe.Pointer) uint64 {
+	return uint64(binary.LittleEndian.Uint32((*[4]byte)(p)[:]))
+}
+
+func le8(p unsafe.Pointer) uint64 {
+	return binary.LittleEndian.Uint64((*[8]byte)(p)[:])
+}
+
+// Hash128 hashes s bytes at p into two 64-bit halves with their own seeds. It
+// is the same on every platform, like Hash64. Every 16 bytes are mixed into
+// both halves with their own multipliers, keyed by the halves themselves, and
+// added to them, so no input makes the halves equal or cancels the state for
+// every seed.
+func Hash128(p unsafe.Pointer, seed1, seed2 uint64, s uintptr) (uint64, uint64) {
+	lo, hi := seed1^m1, seed2^m4^uint64(s)
+	var a, b uint64
+	switch {
+	case s == 0:
+	case s < 4:
+		a = uint64(*(*byte)(p))
+		a |= uint64(*(*byte)(unsafe.Add(p, s>>1))) << 8
+		a |= uint64(*(*byte)(unsafe.Add(p, s-1))) << 16
+	case s <= 8:
+		a = le4(p)
+		b = le4(unsafe.Add(p, s-4))
+	case s <= 16:
+		a = le8(p)
+		b = le8(unsafe.Add(p, s-8))
+	default:
+		l := s
+		for ; l > 16; l -= 16 {
+			lo, hi = round128(lo, hi, le8(p), le8(unsafe.Add(p, 8)))
+			p = unsafe.Add(p, 16)
+		}
+		a = le8(unsafe.Add(p, l-16))
+		b = le8(unsafe.Add(p, l-8))
+	}
+
+	lo, hi = round128(lo, hi, a, b)
+	return mix64(lo^m5, hi^m1), mix64(hi^m3, lo^m2)
+}
+
+func round128(lo, hi, a, b uint64) (uint64, uint64) {
+	lo += mix64(a^hi^m2, b^lo^m3)
+	hi += mix64(b^lo^m4, a^hi^m5)
+	return lo, hi
+}
@@ -0,0 +1,52 @@
+package internal
+
+import (
+	"math/rand"
+	"testing"
+	"unsafe"
+)
+
+func TestHash64MatchesMemhashFallback(t *testing.T) {
+	if !is64Bit || BigEndian {
+		t.Skip("MemhashFallback is not the portable hash on this platform")
+	}
+
+	r := rand.New(rand.NewSource(1234))
+	b := make([]byte, 256)
+	randBytes(r, b)
+	for n := 0; n <= len(b); n++ {
+		seed := r.Uint64()
+		p := unsafe.Pointer(&b[0])
+		if got, want := Hash64(p, seed, uintptr(n)), uint64(MemhashFallback(p, uintptr(seed), uintptr(n))); got != want {
+			t.Fatalf("len %d: got %#x, want %#x", n, got, want)
+		}
+	}
+}
+
+// Golden hashes checked on every platform, e.g. GOARCH=386 go test.
+func TestHash64Golden(t *testing.T) {
+	b := make([]byte, 100)
+	for i := range b {
+		b[i] = byte(i * 7)
+	}
+
+	for _, tt := range []struct {
+		n    int
+		want uint64
+	}{
+		{0, 0xa0761d6478bd6405},
+		{3, 0x3d3b90aaca9b3824},
+		{4, 0x3c849c62ee9e305b},
+		{7, 0x75f658770350c4ac},
+		{8, 0x582b2fff104226e3},
+		{16, 0x4c5d1f8955f39d8e},
+		{17, 0xa5afa640792c11fa},
+		{48, 0x541d27c2cca3f7fa},
+		{49, 0xe25942c63e463670},
+		{100, 0xe2a6d93f1bc16b44},
+	} {
+		if got := Hash64(unsafe.Pointer(&b[0]), 42, uintptr(tt.n)); got != tt.want {
+			t.Errorf("len %d: got %#x, want %#x", tt.n, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+// Package structtag parses the `anyhash` tags of struct fields for anyhash and
+// cmd/anyhashgen, so that both read them the same way.
+package structtag
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Tag is the parsed `anyhash` tag of a struct field:
+//
+//	anyhash:"-"                   the field is not hashed
+//	anyhash:"omitempty"           the zero value of the field is not hashed, so
+//	                              adding such a field keeps the old hashes
+//	anyhash:"unordered"           the elements of a slice or array field are
+//	                              hashed independently of their order
+//	anyhash:"omitempty,unordered" both of them
+type Tag struct {
+	Skip      bool
+	OmitEmpty bool
+	Unordered bool
+}
+
+// Parse parses the value of an `anyhash` tag.
+func Parse(tag string) (Tag, error) {
+	var ft Tag
+	if tag == "" {
+		return ft, nil
+	}
+	if tag == "-" {
+		ft.Skip = true
+		return ft, nil
+	}
+
+	for _, opt := range strings.Split(tag, ",") {
+		var set *bool
+		switch opt {
+		case "omitempty":
+			set = &ft.OmitEmpty
+		case "unordered":
+			set = &ft.Unordered
+		case "":
+			return ft, fmt.Errorf("empty option in tag %q", tag)
+		case "-":
+			return ft, errors.New(`"-" cannot be combined with other options`)
+		default:
+			return ft, fmt.Errorf("unknown option %q", opt)
+		}
+		if *set {
+			return ft, fmt.Errorf("duplicate option %q", opt)
+		}
+		*set = true
+	}
+	return ft, nil
+}
@@ -0,0 +1,73 @@
+package internal
+
+import (
+	"math/bits"
+	"unsafe"
+)
+
+const (
+	xxPrime1 uint64 = 11400714785074694791
+	xxPrime2 uint64 = 14029467366897019727
+	xxPrime3 uint64 = 1609587929392839161
+	xxPrime4 uint64 = 9650029242287828579
+	xxPrime5 uint64 = 2870177450012600261
+)
+
+// XXHash64 is the 64-bit xxHash of s bytes at p.
+func XXHash64(p unsafe.Pointer, seed uint64, s uintptr) uint64 {
+	var h uint64
+	var i uintptr
+	if s >= 32 {
+		v1 := seed + xxPrime1 + xxPrime2
+		v2 := seed + xxPrime2
+		v3 := seed
+		v4 := seed - xxPrime1
+		for ; s-i >= 32; i += 32 {
+			v1 = xxRound(v1, le8(unsafe.Add(p, i)))
+			v2 = xxRound(v2, le8(unsafe.Add(p, i+8)))
+			v3 = xxRound(v3, le8(unsafe.Add(p, i+16)))
+			v4 = xxRound(v4, le8(unsafe.Add(p, i+24)))
+		}
+		h = bits.RotateLeft64(v1, 1) + bits.RotateLeft64(v2, 7) +
+			bits.RotateLeft64(v3, 12) + bits.RotateLeft64(v4, 18)
+		h = xxMerge(h, v1)
+		h = xxMerge(h, v2)
+		h = xxMerge(h, v3)
+		h = xxMerge(h, v4)
+	} else {
+		h = seed + xxPrime5
+	}
+
+	h += uint64(s)
+	for ; s-i >= 8; i += 8 {
+		h ^= xxRound(0, le8(unsafe.Add(p, i)))
+		h = bits.RotateLeft64(h, 27)*xxPrime1 + xxPrime4
+	}
+	if s-i >= 4 {
+		h ^= le4(unsafe.Add(p, i)) * xxPrime1
+		h = bits.RotateLeft64(h, 23)*xxPrime2 + xxPrime3
+		i += 4
+	}
+	for ; i < s; i++ {
+		h ^= uint64(*(*byte)(unsafe.Add(p, i))) * xxPrime5
+		h = bits.RotateLeft64(h, 11) * xxPrime1
+	}
+
+	h ^= h >> 33
+	h *= xxPrime2
+	h ^= h >> 29
+	h *= xxPrime3
+	h ^= h >> 32
+	return h
+}
+
+func xxRound(acc, input uint64) uint64 {
+	acc += input * xxPrime2
+	acc = bits.RotateLeft64(acc, 31)
+	return acc * xxPrime1
+}
+
+func xxMerge(acc, v uint64) uint64 {
+	acc ^= xxRound(0, v)
+	return acc*xxPrime1 + xxPrime4
+}
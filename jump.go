@@ -0,0 +1,25 @@
+package anyhash
+
+// Jump returns the bucket of v in [0, buckets) by the jump consistent hash of
+// Lamping and Veach. Buckets get values evenly, without the bias of taking
+// the hash modulo buckets, and growing from n to n+1 buckets moves only the
+// values that fall into the new bucket. Buckets cannot be removed but from
+// the end; use Ring or Rendezvous for arbitrary nodes.
+func Jump[T any](h *AnyHasher[T], v T, buckets int) int {
+	if buckets < 1 {
+		panic("anyhash: jump hash needs at least one bucket")
+	}
+	return jump(h.GetHash64(v), buckets)
+}
+
+// jump follows the reference implementation, with the key as the state of a
+// linear congruential generator that picks the next bucket the key jumps to.
+func jump(key uint64, buckets int) int {
+	b, j := int64(-1), int64(0)
+	for j < int64(buckets) {
+		b = j
+		key = key*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
+	}
+	return int(b)
+}
@@ -0,0 +1,102 @@
+package anyhash
+
+import (
+	"math"
+	"testing"
+)
+
+func TestJump(t *testing.T) {
+	h := newTestHasher[testKey](t, WithPortable())
+	n := 100000
+	if testing.Short() {
+		n = 20000
+	}
+	keys := testKeys(0, n)
+
+	t.Run("One", func(t *testing.T) {
+		for _, k := range keys[:100] {
+			if got := Jump(h, k, 1); got != 0 {
+				t.Fatalf("got %d, want 0", got)
+			}
+		}
+	})
+
+	t.Run("Distribution", func(t *testing.T) {
+		// With 3 buckets, the top third of 64-bit hashes modulo 3 would be
+		// biased; jump hash has no such bias.
+		for name, opts := range testHasherOptions {
+			h := newTestHasher[testKey](t, opts...)
+			for _, buckets := range []int{3, 10, 37} {
+				loads := make([]int, buckets)
+				for _, k := range keys {
+					b := Jump(h, k, buckets)
+					if b < 0 || b >= buckets {
+						t.Fatalf("%s: got bucket %d, want [0, %d)", name, b, buckets)
+					}
+					loads[b]++
+				}
+
+				// The bound is 6 standard deviations of the binomial count.
+				p := 1 / float64(buckets)
+				mean, dev := float64(n)*p, math.Sqrt(float64(n)*p*(1-p))
+				for b, load := range loads {
+					if math.Abs(float64(load)-mean) > 6*dev {
+						t.Fatalf("%s: %d buckets: bucket %d got %d keys, want %.0f±%.0f", name, buckets, b, load, mean, 6*dev)
+					}
+				}
+			}
+		}
+	})
+
+	t.Run("Disruption", func(t *testing.T) {
+		for _, buckets := range []int{1, 5, 16, 99} {
+			moved := 0
+			for _, k := range keys {
+				before, after := Jump(h, k, buckets), Jump(h, k, buckets+1)
+				if before == after {
+					continue
+				}
+				if after != buckets {
+					t.Fatalf("%v moved from %d to %d, not to the new bucket", k, before, after)
+				}
+				moved++
+			}
+
+			want := float64(n) / float64(buckets+1)
+			if d := math.Abs(float64(moved)-want) / want; d > 0.1 && math.Abs(float64(moved)-want) > 100 {
+				t.Fatalf("%d buckets: got %d moved keys, want %.0f", buckets, moved, want)
+			}
+		}
+	})
+
+	t.Run("Golden", func(t *testing.T) {
+		// Portable hashes and jump hash are the same on every platform, so
+		// are the buckets.
+		for i, want := range []int{645, 362, 162, 355, 381, 559} {
+			if got := Jump(h, keys[i], 1000); got != want {
+				t.Fatalf("%v: got %d, want %d", keys[i], got, want)
+			}
+		}
+		for _, tt := range []struct {
+			key           uint64
+			buckets, want int
+		}{
+			{0, 1000, 0},
+			{1, 1000, 549},
+			{^uint64(0), 1000, 313},
+			{0xdeadbeef, 1 << 20, 479362},
+		} {
+			if got := jump(tt.key, tt.buckets); got != tt.want {
+				t.Fatalf("jump(%#x, %d): got %d, want %d", tt.key, tt.buckets, got, tt.want)
+			}
+		}
+	})
+}
+
+func BenchmarkJump(b *testing.B) {
+	h := newTestHasher[testKey](b)
+	ks := testKeys(0, 1024)
+	for i := 0; i < b.N; i++ {
+		Jump(h, ks[i%len(ks)], 1000)
+	}
+}
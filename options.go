@@ -0,0 +1,48 @@
+package anyhash
+
+type options struct {
+	framed    bool
+	rawFloats bool
+	portable  bool
+	alg       Algorithm
+}
+
+type Option func(*options)
+
+// WithFraming delimits every variable-length segment (strings, slices) with its
+// length, so structurally different values never produce the same input stream.
+// Nil slices are framed differently from empty ones.
+func WithFraming() Option {
+	return func(o *options) {
+		o.framed = true
+	}
+}
+
+// WithRawFloats hashes floats and complex numbers by their bits as they are.
+// By default -0 hashes as 0 and all NaNs hash alike, following ==.
+func WithRawFloats() Option {
+	return func(o *options) {
+		o.rawFloats = true
+	}
+}
+
+// WithPortable makes GetHash64 return the same hash for the same value on every
+// platform, so hashes can be persisted and shared between services. Numbers
+// are hashed as little endian bytes, int, uint and uintptr as 64 bits, and the
+// hash function is 64-bit everywhere. On 64-bit little endian platforms the
+// hashes equal the ones of a hasher without this option. Seeds wider than 32
+// bits need NewPortable, as uint has 32 bits on 32-bit platforms.
+func WithPortable() Option {
+	return func(o *options) {
+		o.portable = true
+	}
+}
+
+// WithAlgorithm hashes values with a instead of the native MemhashFallback.
+// The algorithms of this package return the same hash for the same bytes on
+// every platform; combined WithPortable, so do the hashes of values.
+func WithAlgorithm(a Algorithm) Option {
+	return func(o *options) {
+		o.alg = a
+	}
+}
@@ -0,0 +1,70 @@
+package anyhash
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+// span is a run of meaningful bytes inside a value.
+type span struct {
+	offset uintptr
+	size   uintptr
+}
+
+// spansOf appends the meaningful bytes of the pointer-free type typ placed at
+// offset to spans, merging adjacent runs. Padding between and after struct
+// fields is left out.
+func spansOf(typ reflect.Type, offset uintptr, spans []span) []span {
+	switch typ.Kind() {
+	case reflect.Array:
+		for i := 0; i < typ.Len(); i++ {
+			spans = spansOf(typ.Elem(), offset+uintptr(i)*typ.Elem().Size(), spans)
+		}
+	case reflect.Struct:
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			spans = spansOf(field.Type, offset+field.Offset, spans)
+		}
+	default:
+		if typ.Size() == 0 {
+			break
+		}
+		if n := len(spans); n != 0 && spans[n-1].offset+spans[n-1].size == offset {
+			spans[n-1].size += typ.Size()
+			break
+		}
+		spans = append(spans, span{offset, typ.Size()})
+	}
+	return spans
+}
+
+// paddingSpans returns the meaningful bytes of typ, or nil if typ has no
+// padding and can be hashed as it lies in memory.
+func paddingSpans(typ reflect.Type) []span {
+	spans := spansOf(typ, 0, nil)
+	if len(spans) == 1 && spans[0].size == typ.Size() || len(spans) == 0 && typ.Size() == 0 {
+		return nil
+	}
+	return spans
+}
+
+// compactBlock copies the meaningful bytes of the n elements at p next to each
+// other and returns the copy with its size.
+func compactBlock(p unsafe.Pointer, n int, elemSz uintptr, spans []span) (unsafe.Pointer, uintptr) {
+	var meaningful uintptr
+	for _, s := range spans {
+		meaningful += s.size
+	}
+	if n == 0 || meaningful == 0 {
+		return p, 0
+	}
+
+	block := make([]byte, 0, uintptr(n)*meaningful)
+	for i := 0; i < n; i++ {
+		elem := unsafe.Add(p, uintptr(i)*elemSz)
+		for _, s := range spans {
+			block = append(block, unsafe.Slice((*byte)(unsafe.Add(elem, s.offset)), s.size)...)
+		}
+	}
+	return unsafe.Pointer(&block[0]), uintptr(len(block))
+}
@@ -0,0 +1,72 @@
+package anyhash
+
+import (
+	"reflect"
+	"testing"
+	"unsafe"
+)
+
+type testPadded struct {
+	a int8
+	b int64
+	c int16
+}
+
+func TestPaddingSpans(t *testing.T) {
+	var p testPadded
+	var s struct {
+		p testPadded
+		d int32
+	}
+	tests := []struct {
+		name string
+		typ  reflect.Type
+		want []span
+	}{
+		{"NoPadding", reflect.TypeOf(struct {
+			a int16
+			b [2]byte
+		}{}), nil},
+		{"Empty", reflect.TypeOf(struct{}{}), nil},
+		{"Int64", reflect.TypeOf(int64(0)), nil},
+		{"Struct", reflect.TypeOf(p), []span{{0, 1}, {unsafe.Offsetof(p.b), 10}}},
+		{"Array[Struct]", reflect.TypeOf([2]struct {
+			a int16
+			b int8
+		}{}), []span{{0, 3}, {4, 3}}},
+		{"Struct[Struct]", reflect.TypeOf(s), []span{{0, 1}, {unsafe.Offsetof(p.b), 10}, {unsafe.Offsetof(s.d), 4}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := paddingSpans(tt.typ); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPadding(t *testing.T) {
+	t.Run("Slice[Struct]", testType([]testPadded{{1, 2, 3}}, []byte{1, 2, 0, 0, 0, 0, 0, 0, 0, 3, 0}))
+	t.Run("Array[Struct]", testType([2]struct {
+		a int16
+		b int8
+	}{{1, 2}, {3, 4}}, []byte{1, 0, 2, 3, 0, 4}))
+
+	dirty := []testPadded{{1, 2, 3}, {-1, -2, -3}}
+	clean := []testPadded{{1, 2, 3}, {-1, -2, -3}}
+	for i := range dirty {
+		b := unsafe.Slice((*byte)(unsafe.Pointer(&dirty[i])), unsafe.Sizeof(dirty[i]))
+		for j := uintptr(1); j < unsafe.Offsetof(dirty[i].b); j++ {
+			b[j] = 0xff
+		}
+		for j := unsafe.Offsetof(dirty[i].c) + 2; j < uintptr(len(b)); j++ {
+			b[j] = 0xff
+		}
+	}
+	t.Run("Garbage", testEqual(nil, clean, dirty))
+
+	dirtyArr := *(*[2]testPadded)(dirty)
+	cleanArr := *(*[2]testPadded)(clean)
+	t.Run("GarbageArray", testEqual(nil, &cleanArr, &dirtyArr))
+}
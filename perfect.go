@@ -0,0 +1,311 @@
+package anyhash
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/bits"
+)
+
+// PerfectHash is a minimal perfect hash function of a set of values of any
+// type hashable by AnyHasher: it maps the n values of the set to distinct
+// indices in [0, n), so that they can index a table without collisions and
+// without storing the values. Values not in the set get arbitrary indices in
+// [0, n).
+//
+// It follows the hash and displace scheme of CHD and PTHash: values are split
+// into buckets of a few values, and every bucket gets a displacement that
+// places its values in free slots of a table slightly larger than n. The
+// slots past n are then remapped to the free ones below n. It takes about
+// 8.5 bits per value, and building it takes about a microsecond per value.
+type PerfectHash[T any] struct {
+	h *AnyHasher[T]
+	perfectShape
+}
+
+// perfectShape maps hashes to indices.
+type perfectShape struct {
+	seed  uint64
+	n     uint64
+	slots uint64
+	disps []uint32
+	remap []uint32
+}
+
+const (
+	// perfectBucketSize is the average number of values per bucket.
+	perfectBucketSize = 4
+	// perfectLoad is the share of taken slots of the table.
+	perfectLoad = 0.99
+	// maxPerfectDisp bounds the displacements tried for a bucket before
+	// trying another seed.
+	maxPerfectDisp = 1 << 20
+	// maxPerfectAttempts bounds the seeds tried by NewPerfectHash.
+	maxPerfectAttempts = 100
+)
+
+// NewPerfectHash returns the minimal perfect hash function of values hashed
+// with h. It is an error if two values are equal or have equal hashes. Equal
+// 64-bit hashes are likely only for billions of values, but native hashes of
+// 32-bit platforms collide for tens of thousands, so h must hash WithPortable
+// or WithAlgorithm there.
+func NewPerfectHash[T any](h *AnyHasher[T], values []T) (*PerfectHash[T], error) {
+	if !h.hashes64() {
+		return nil, errors.New("anyhash: perfect hash needs 64-bit hashes, use WithPortable")
+	}
+	slots := uint64(math.Ceil(float64(len(values)) / perfectLoad))
+	if slots > math.MaxUint32 {
+		return nil, errors.New("anyhash: too many values for a perfect hash")
+	}
+
+	hashes := make([]uint64, len(values))
+	for i, v := range values {
+		hashes[i] = h.GetHash64(v)
+	}
+	sortHashes(hashes)
+	for i := 1; i < len(hashes); i++ {
+		if hashes[i] == hashes[i-1] {
+			return nil, errors.New("anyhash: perfect hash of equal values or values with equal hashes")
+		}
+	}
+
+	p := &PerfectHash[T]{h: h}
+	p.n = uint64(len(hashes))
+	p.slots = slots
+	p.disps = make([]uint32, (p.n+perfectBucketSize-1)/perfectBucketSize)
+	b := perfectBuilder{taken: make([]uint64, (p.slots+63)/64)}
+	for attempt := 0; ; attempt++ {
+		if attempt == maxPerfectAttempts {
+			return nil, errors.New("anyhash: perfect hash construction failed")
+		}
+		p.seed = combineHashes(perfectSeed, uint64(attempt))
+		if b.place(&p.perfectShape, hashes) {
+			break
+		}
+	}
+
+	// Slots past n are taken by as many values as there are free slots below
+	// n.
+	if p.slots > p.n {
+		p.remap = make([]uint32, p.slots-p.n)
+		free := uint64(0)
+		for slot := p.n; slot < p.slots; slot++ {
+			if !b.isTaken(slot) {
+				continue
+			}
+			for b.isTaken(free) {
+				free++
+			}
+			p.remap[slot-p.n] = uint32(free)
+			free++
+		}
+	}
+	return p, nil
+}
+
+const perfectSeed = 0x6d70_6866_5f68_6173
+
+// Index returns the index of v in [0, n). It is distinct for every value of
+// the set and arbitrary for others, so tables indexed by it must hold the
+// values or their hashes if other values are looked up.
+func (p *PerfectHash[T]) Index(v T) int {
+	if p.n == 0 {
+		return 0
+	}
+	return int(p.index(p.h.GetHash64(v)))
+}
+
+// Len returns the number of values of the set.
+func (p *PerfectHash[T]) Len() int {
+	return int(p.n)
+}
+
+// Bits returns the size of the function in bits.
+func (p *PerfectHash[T]) Bits() int {
+	return 32 * (len(p.disps) + len(p.remap))
+}
+
+func (p *perfectShape) index(keyHash uint64) uint64 {
+	k := combineHashes(p.seed, keyHash)
+	slot := p.slot(k, p.dispHash(p.disps[p.bucket(k)]))
+	if slot >= p.n {
+		return uint64(p.remap[slot-p.n])
+	}
+	return slot
+}
+
+func (p *perfectShape) bucket(k uint64) uint64 {
+	hi, _ := bits.Mul64(k, uint64(len(p.disps)))
+	return hi
+}
+
+// slot returns the slot of the mixed key hash k displaced by the hash of a
+// displacement. Displacements are hashed once for all the keys of a bucket,
+// as in PTHash, and the keys are mixed with them by the finalizer of
+// MurmurHash3, as the keys of a bucket share their high bits.
+func (p *perfectShape) slot(k, dispHash uint64) uint64 {
+	x := k ^ dispHash
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	hi, _ := bits.Mul64(x, p.slots)
+	return hi
+}
+
+func (p *perfectShape) dispHash(disp uint32) uint64 {
+	return combineHashes(p.seed, uint64(disp))
+}
+
+// perfectBuilder places buckets in the slots of a table, the largest bucket
+// first, when there are the most free slots.
+type perfectBuilder struct {
+	taken []uint64
+	keys  []uint64
+	slots []uint64
+}
+
+func (b *perfectBuilder) isTaken(slot uint64) bool {
+	return b.taken[slot/64]&(1<<(slot%64)) != 0
+}
+
+// place finds a displacement for every bucket of the key hashes mixed with
+// the seed of p and reports whether it did.
+func (b *perfectBuilder) place(p *perfectShape, keyHashes []uint64) bool {
+	for i := range b.taken {
+		b.taken[i] = 0
+	}
+
+	// Group the keys by bucket, then the buckets by size.
+	buckets := len(p.disps)
+	starts := make([]int, buckets+1)
+	b.keys = b.keys[:0]
+	for _, kh := range keyHashes {
+		k := combineHashes(p.seed, kh)
+		b.keys = append(b.keys, k)
+		starts[p.bucket(k)+1]++
+	}
+	maxSize := 0
+	for i := 1; i <= buckets; i++ {
+		if starts[i] > maxSize {
+			maxSize = starts[i]
+		}
+		starts[i] += starts[i-1]
+	}
+	grouped := make([]uint64, len(b.keys))
+	next := append([]int(nil), starts[:buckets]...)
+	for _, k := range b.keys {
+		i := p.bucket(k)
+		grouped[next[i]] = k
+		next[i]++
+	}
+
+	bySize := make([][]uint32, maxSize+1)
+	for i := 0; i < buckets; i++ {
+		size := starts[i+1] - starts[i]
+		bySize[size] = append(bySize[size], uint32(i))
+	}
+
+	for size := maxSize; size > 0; size-- {
+		for _, bucket := range bySize[size] {
+			keys := grouped[starts[bucket]:starts[bucket+1]]
+			disp, ok := b.displace(p, keys)
+			if !ok {
+				return false
+			}
+			p.disps[bucket] = disp
+		}
+	}
+	for _, bucket := range bySize[0] {
+		p.disps[bucket] = 0
+	}
+	return true
+}
+
+// displace returns the first displacement that places keys in distinct free
+// slots and takes them.
+func (b *perfectBuilder) displace(p *perfectShape, keys []uint64) (uint32, bool) {
+next:
+	for disp := uint32(0); disp < maxPerfectDisp; disp++ {
+		dispHash := p.dispHash(disp)
+		b.slots = b.slots[:0]
+		for _, k := range keys {
+			slot := p.slot(k, dispHash)
+			if b.isTaken(slot) {
+				continue next
+			}
+			for _, other := range b.slots {
+				if other == slot {
+					continue next
+				}
+			}
+			b.slots = append(b.slots, slot)
+		}
+
+		for _, slot := range b.slots {
+			b.taken[slot/64] |= 1 << (slot % 64)
+		}
+		return disp, true
+	}
+	return 0, false
+}
+
+// perfectMagic starts the binary form of a PerfectHash, whose fields are the
+// seed, the number of values, slots and buckets, the displacements and the
+// remapped slots.
+const perfectMagic = "AHP1"
+
+// MarshalBinary returns the binary form of p, which UnmarshalPerfectHash
+// reads back, so that p can be built offline and loaded at startup.
+func (p *PerfectHash[T]) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 0, binaryHeaderSize+20+4*(len(p.disps)+len(p.remap)))
+	b = p.h.appendHeader(b, perfectMagic)
+	b = appendUint64(b, p.seed)
+	b = appendUint32(b, uint32(p.n))
+	b = appendUint32(b, uint32(p.slots))
+	b = appendUint32(b, uint32(len(p.disps)))
+	for _, d := range p.disps {
+		b = appendUint32(b, d)
+	}
+	for _, r := range p.remap {
+		b = appendUint32(b, r)
+	}
+	return b, nil
+}
+
+// UnmarshalPerfectHash returns the function in the binary form data, made by
+// MarshalBinary of a function with a hasher compatible with h, as told by
+// Fingerprint.
+func UnmarshalPerfectHash[T any](h *AnyHasher[T], data []byte) (*PerfectHash[T], error) {
+	data, err := h.readHeader(data, perfectMagic, "perfect hash", 20)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &PerfectHash[T]{h: h}
+	p.seed = binary.LittleEndian.Uint64(data)
+	p.n = uint64(binary.LittleEndian.Uint32(data[8:]))
+	p.slots = uint64(binary.LittleEndian.Uint32(data[12:]))
+	buckets := uint64(binary.LittleEndian.Uint32(data[16:]))
+	data = data[20:]
+	if p.slots < p.n || (p.n > 0) != (buckets > 0) ||
+		uint64(len(data)) != 4*(buckets+p.slots-p.n) {
+		return nil, errors.New("anyhash: invalid perfect hash data")
+	}
+
+	p.disps = make([]uint32, buckets)
+	for i := range p.disps {
+		p.disps[i] = binary.LittleEndian.Uint32(data[4*i:])
+	}
+	data = data[4*buckets:]
+	if p.slots > p.n {
+		p.remap = make([]uint32, p.slots-p.n)
+		for i := range p.remap {
+			if p.remap[i] = binary.LittleEndian.Uint32(data[4*i:]); uint64(p.remap[i]) >= p.n {
+				return nil, errors.New("anyhash: invalid perfect hash data")
+			}
+		}
+	}
+	return p, nil
+}
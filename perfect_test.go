@@ -0,0 +1,147 @@
+package anyhash
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// testMinimalPerfect checks that p maps values to a permutation of [0, n).
+func testMinimalPerfect(t *testing.T, p *PerfectHash[testKey], values []testKey) {
+	if p.Len() != len(values) {
+		t.Fatalf("got %d values, want %d", p.Len(), len(values))
+	}
+	seen := make([]bool, len(values))
+	for _, v := range values {
+		i := p.Index(v)
+		if i < 0 || i >= len(values) {
+			t.Fatalf("%v: got index %d, want [0, %d)", v, i, len(values))
+		}
+		if seen[i] {
+			t.Fatalf("%v: index %d is taken", v, i)
+		}
+		seen[i] = true
+	}
+}
+
+func TestPerfectHash(t *testing.T) {
+	n := 200000
+	if testing.Short() {
+		n = 20000
+	}
+	values := testKeys(0, n)
+	h := newTestHasher[testKey](t, WithPortable())
+
+	t.Run("Minimal", func(t *testing.T) {
+		for _, size := range []int{0, 1, 2, 3, 4, 5, 10, 100, 1000, n} {
+			testMinimalPerfect(t, must(NewPerfectHash(h, values[:size]))(t), values[:size])
+		}
+	})
+
+	t.Run("Native", func(t *testing.T) {
+		// Native hashes are 32-bit on 32-bit platforms.
+		p, err := NewPerfectHash(newTestHasher[testKey](t), values)
+		if (err == nil) != is64Bit {
+			t.Fatalf("got err %v on a platform with 64-bit ints %t", err, is64Bit)
+		}
+		if err == nil {
+			testMinimalPerfect(t, p, values)
+		}
+	})
+
+	t.Run("Size", func(t *testing.T) {
+		p := must(NewPerfectHash(h, values))(t)
+		if got := float64(p.Bits()) / float64(n); got > 9 {
+			t.Fatalf("got %.2f bits per value, want at most 9", got)
+		}
+	})
+
+	t.Run("Others", func(t *testing.T) {
+		p := must(NewPerfectHash(h, values[:1000]))(t)
+		for _, v := range values[1000:2000] {
+			if i := p.Index(v); i < 0 || i >= 1000 {
+				t.Fatalf("%v: got index %d, want [0, 1000)", v, i)
+			}
+		}
+	})
+
+	t.Run("Order", func(t *testing.T) {
+		shuffled := append([]testKey{}, values[:1000]...)
+		rand.New(rand.NewSource(1)).Shuffle(len(shuffled), func(i, j int) {
+			shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+		})
+
+		p, q := must(NewPerfectHash(h, values[:1000]))(t), must(NewPerfectHash(h, shuffled))(t)
+		for _, v := range values[:1000] {
+			if p.Index(v) != q.Index(v) {
+				t.Fatalf("%v: got indices %d and %d", v, p.Index(v), q.Index(v))
+			}
+		}
+	})
+
+	t.Run("Duplicates", func(t *testing.T) {
+		dup := append(testKeys(0, 10), testKeys(0, 1)...)
+		if _, err := NewPerfectHash(h, dup); err == nil || !strings.Contains(err.Error(), "equal values") {
+			t.Fatalf("got err %v, want equal values", err)
+		}
+	})
+
+	t.Run("Binary", func(t *testing.T) {
+		p := must(NewPerfectHash(h, values[:10000]))(t)
+		data, err := p.MarshalBinary()
+		if err != nil {
+			t.Fatalf("expected nil err, got %s", err)
+		}
+
+		q, err := UnmarshalPerfectHash(h, data)
+		if err != nil {
+			t.Fatalf("expected nil err, got %s", err)
+		}
+		for _, v := range values[:20000] {
+			if p.Index(v) != q.Index(v) {
+				t.Fatalf("%v: got index %d, want %d", v, q.Index(v), p.Index(v))
+			}
+		}
+
+		empty, err := must(NewPerfectHash(h, nil))(t).MarshalBinary()
+		if err != nil {
+			t.Fatalf("expected nil err, got %s", err)
+		}
+		if q, err := UnmarshalPerfectHash(h, empty); err != nil || q.Len() != 0 {
+			t.Fatalf("got %v and err %v, want empty function", q, err)
+		}
+
+		badRemap := append([]byte{}, data...)
+		copy(badRemap[len(badRemap)-4:], []byte{0xff, 0xff, 0xff, 0xff})
+		testUnmarshalErrors(t, data, UnmarshalPerfectHash[testKey], map[string]testBinaryCase{
+			"Remap": {badRemap, "invalid"},
+		})
+	})
+}
+
+func BenchmarkPerfectHash(b *testing.B) {
+	h := newTestHasher[testKey](b)
+	values := testKeys(0, 1000000)
+
+	b.Run("Build", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			p, err := NewPerfectHash(h, values)
+			if err != nil {
+				b.Fatalf("expected nil err, got %s", err)
+			}
+			b.ReportMetric(float64(p.Bits())/float64(len(values)), "bits/value")
+		}
+	})
+
+	b.Run("Index", func(b *testing.B) {
+		p, err := NewPerfectHash(h, values)
+		if err != nil {
+			b.Fatalf("expected nil err, got %s", err)
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			p.Index(values[i%len(values)])
+		}
+	})
+}
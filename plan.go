@@ -0,0 +1,30 @@
+package anyhash
+
+import "unsafe"
+
+type planStep interface {
+	writeTo(w *writer, p unsafe.Pointer)
+
+	// equal reports whether the parts of a and b the step hashes are equal.
+	equal(e *equaler, a, b unsafe.Pointer) bool
+}
+
+// plan is the list of steps hashing a value of one type.
+type plan struct {
+	steps []planStep
+}
+
+func (pl *plan) writeTo(w *writer, p unsafe.Pointer) {
+	for _, step := range pl.steps {
+		step.writeTo(w, p)
+	}
+}
+
+func (pl *plan) equal(e *equaler, a, b unsafe.Pointer) bool {
+	for _, step := range pl.steps {
+		if !step.equal(e, a, b) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,152 @@
+package anyhash
+
+import (
+	"bytes"
+	"reflect"
+	"unsafe"
+
+	"github.com/hikitani/anyhash/internal"
+)
+
+const is64Bit = ^uint(0)>>63 == 1
+
+// hashes64 reports whether GetHash64 of h has 64 bits. Native hashes have as
+// many bits as uintptr.
+func (h *AnyHasher[T]) hashes64() bool {
+	return h.opts.portable || h.opts.alg != nil || is64Bit
+}
+
+type scalarKind uint8
+
+const (
+	scalarFixed scalarKind = iota
+	scalarInt
+	scalarUint
+	scalarFloat
+)
+
+// scalar is a number or bool inside a value, hashed by portable hashers as
+// little endian bytes with int, uint and uintptr widened to 64 bits.
+type scalar struct {
+	offset uintptr
+	size   uintptr
+	kind   scalarKind
+}
+
+// scalarsOf appends the scalars of the pointer-free type typ placed at offset
+// to scalars.
+func scalarsOf(typ reflect.Type, offset uintptr, scalars []scalar) []scalar {
+	switch k := typ.Kind(); k {
+	case reflect.Array:
+		for i := 0; i < typ.Len(); i++ {
+			scalars = scalarsOf(typ.Elem(), offset+uintptr(i)*typ.Elem().Size(), scalars)
+		}
+	case reflect.Struct:
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			scalars = scalarsOf(field.Type, offset+field.Offset, scalars)
+		}
+	case reflect.Int:
+		scalars = append(scalars, scalar{offset, typ.Size(), scalarInt})
+	case reflect.Uint, reflect.Uintptr:
+		scalars = append(scalars, scalar{offset, typ.Size(), scalarUint})
+	case reflect.Float32, reflect.Float64:
+		scalars = append(scalars, scalar{offset, typ.Size(), scalarFloat})
+	case reflect.Complex64, reflect.Complex128:
+		sz := typ.Size() / 2
+		scalars = append(scalars, scalar{offset, sz, scalarFloat}, scalar{offset + sz, sz, scalarFloat})
+	default:
+		scalars = append(scalars, scalar{offset, typ.Size(), scalarFixed})
+	}
+	return scalars
+}
+
+// needsEncoding reports whether the scalars lie in memory differently from
+// their portable bytes: multi-byte scalars on big endian platforms and
+// platform-sized integers narrower than 64 bits.
+func needsEncoding(scalars []scalar) bool {
+	for _, s := range scalars {
+		if internal.BigEndian && s.size > 1 {
+			return true
+		}
+		if !is64Bit && (s.kind == scalarInt || s.kind == scalarUint) {
+			return true
+		}
+	}
+	return false
+}
+
+// appendScalars appends the portable bytes of the scalars of the value at p
+// to b, making floats canonical unless rawFloats is set.
+func appendScalars(b []byte, p unsafe.Pointer, scalars []scalar, rawFloats bool) []byte {
+	for _, s := range scalars {
+		sp := unsafe.Add(p, s.offset)
+		switch {
+		case s.kind == scalarInt:
+			b = appendLittleEndian(b, uint64(*(*int)(sp)), 8)
+		case s.kind == scalarUint:
+			b = appendLittleEndian(b, uint64(*(*uintptr)(sp)), 8)
+		case s.size == 1:
+			b = append(b, *(*byte)(sp))
+		case s.size == 2:
+			b = appendLittleEndian(b, uint64(*(*uint16)(sp)), 2)
+		case s.size == 4:
+			v := *(*uint32)(sp)
+			if s.kind == scalarFloat && !rawFloats {
+				v = canonical32(v)
+			}
+			b = appendLittleEndian(b, uint64(v), 4)
+		case s.size == 8:
+			v := *(*uint64)(sp)
+			if s.kind == scalarFloat && !rawFloats {
+				v = canonical64(v)
+			}
+			b = appendLittleEndian(b, v, 8)
+		}
+	}
+	return b
+}
+
+func appendLittleEndian(b []byte, v uint64, size int) []byte {
+	for i := 0; i < size; i++ {
+		b = append(b, byte(v>>(8*i)))
+	}
+	return b
+}
+
+// encodeBlock returns the portable bytes of the n elements at p.
+func encodeBlock(p unsafe.Pointer, n int, elemSz uintptr, scalars []scalar, rawFloats bool) []byte {
+	var b []byte
+	for i := 0; i < n; i++ {
+		b = appendScalars(b, unsafe.Add(p, uintptr(i)*elemSz), scalars, rawFloats)
+	}
+	return b
+}
+
+// scalarGetter hashes a number or bool by its portable bytes.
+type scalarGetter struct {
+	offset    uintptr
+	ptrDepth  int
+	scalars   []scalar
+	rawFloats bool
+}
+
+func (s *scalarGetter) writeTo(w *writer, p unsafe.Pointer) {
+	np := deref(w, p, s.offset, s.ptrDepth)
+	if np == nil {
+		return
+	}
+
+	var buf [16]byte
+	w.writeBytes(appendScalars(buf[:0], np, s.scalars, s.rawFloats))
+}
+
+func (s *scalarGetter) equal(e *equaler, a, b unsafe.Pointer) bool {
+	na, nb, equal, done := derefEqual(a, b, s.offset, s.ptrDepth)
+	if done {
+		return equal
+	}
+
+	var ba, bb [16]byte
+	return bytes.Equal(appendScalars(ba[:0], na, s.scalars, s.rawFloats), appendScalars(bb[:0], nb, s.scalars, s.rawFloats))
+}
@@ -0,0 +1,115 @@
+package anyhash
+
+import (
+	"math"
+	"testing"
+
+	"github.com/hikitani/anyhash/internal"
+)
+
+type testPortable struct {
+	I    int
+	U    uint
+	P    uintptr
+	I16  int16
+	F32  float32
+	C128 complex128
+	S    string
+	B    []byte
+	Is   []int
+	Arr  [2]struct {
+		A int8
+		B int64
+	}
+	Ptr *int32
+	M   map[string]uint
+	Any any
+	Nil *testPortable
+}
+
+// Golden hashes checked on every platform, e.g. GOARCH=386 go test.
+func TestPortableGolden(t *testing.T) {
+	i32 := int32(-7)
+	full := testPortable{
+		I:    -1,
+		U:    math.MaxUint32,
+		P:    42,
+		I16:  -2,
+		F32:  float32(math.Copysign(0, -1)),
+		C128: complex(1.5, math.NaN()),
+		S:    "portable",
+		B:    []byte{1, 2, 3},
+		Is:   []int{1, -1, 1 << 30},
+		Arr: [2]struct {
+			A int8
+			B int64
+		}{{1, -1}, {2, 1 << 40}},
+		Ptr: &i32,
+		M:   map[string]uint{"a": 1, "b": 2},
+		Any: []int{3},
+	}
+
+	t.Run("Int", testGolden(-1, 0xf3910e9c60d79f12))
+	t.Run("Uint", testGolden(uint(1<<31), 0x4b6f931c9e4b3477))
+	t.Run("Uintptr", testGolden(uintptr(7), 0xd0043602a071e173))
+	t.Run("Int64", testGolden(int64(-1<<40), 0xb9d04751f72c392c))
+	t.Run("Float64", testGolden(math.Pi, 0x5664a7d26860ac8a))
+	t.Run("String", testGolden("hello, world", 0xecb6da49bf105fd2))
+	t.Run("Slice[int]", testGolden([]int{1, 2, 3, -4}, 0x7f3d9a4c314e055e))
+	t.Run("Slice[uint16]", testGolden([]uint16{1, 0xff00}, 0xf3ad2fbc124659c5))
+	t.Run("Array[float32]", testGolden([3]float32{1, float32(math.Inf(-1)), 0.5}, 0x8c114e3f258bc918))
+	t.Run("Map[int]int", testGolden(map[int]int{1: 10, 2: 20}, 0x3de9d7454cd42738))
+	t.Run("Struct", testGolden(full, 0x24094420369253fa))
+	t.Run("StructFramed", testGolden(full, 0x2dfdbf093faa455e, WithFraming()))
+	t.Run("Zero", testGolden(testPortable{}, 0xcf2bce7c0c5ff545))
+}
+
+func TestNewPortable(t *testing.T) {
+	h, err := NewPortable[string](1<<40 | 1)
+	if err != nil {
+		t.Fatalf("expected nil err, got %s", err)
+	}
+	if got, want := h.GetHash64("hello, world"), uint64(0x95b0376a6f8972ec); got != want {
+		t.Fatalf("got %#x, want %#x", got, want)
+	}
+
+	h1, err := NewPortable[string](1)
+	if err != nil {
+		t.Fatalf("expected nil err, got %s", err)
+	}
+	if h.GetHash64("hello, world") == h1.GetHash64("hello, world") {
+		t.Fatal("seeds differing above 32 bits give the same hash")
+	}
+}
+
+func testGolden[T any](v T, want uint64, opts ...Option) func(t *testing.T) {
+	return func(t *testing.T) {
+		h, err := New[T](1, append(opts, WithPortable())...)
+		if err != nil {
+			t.Fatalf("expected nil err, got %s", err)
+		}
+
+		if got := h.GetHash64(v); got != want {
+			t.Fatalf("got %#x, want %#x", got, want)
+		}
+	}
+}
+
+func TestPortableMatchesNative(t *testing.T) {
+	if !is64Bit || internal.BigEndian {
+		t.Skip("native hashes differ from portable ones on this platform")
+	}
+
+	v := testPortable{I: -1, S: "native", Is: []int{1, 2}, M: map[string]uint{"a": 1}}
+	h, err := New[testPortable](3)
+	if err != nil {
+		t.Fatalf("expected nil err, got %s", err)
+	}
+	hp, err := New[testPortable](3, WithPortable())
+	if err != nil {
+		t.Fatalf("expected nil err, got %s", err)
+	}
+	if got, want := hp.GetHash64(v), h.GetHash64(v); got != want {
+		t.Fatalf("got %#x, want %#x", got, want)
+	}
+}
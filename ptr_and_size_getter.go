@@ -1,23 +1,38 @@
 package anyhash
 
 import (
+	"bytes"
 	"reflect"
 	"unsafe"
 )
 
-func indirect(p unsafe.Pointer, depth int) unsafe.Pointer {
-	switch depth {
-	case 0:
-		return p
-	case 1:
-		return *(*unsafe.Pointer)(p)
+// indirect follows depth pointers starting at p. When it meets a nil pointer
+// it returns nil and the number of pointers followed before it.
+func indirect(p unsafe.Pointer, depth int) (unsafe.Pointer, int) {
+	for i := 0; i < depth; i++ {
+		p = *(*unsafe.Pointer)(p)
+		if p == nil {
+			return nil, i
+		}
 	}
 
-	return indirect(*(*unsafe.Pointer)(p), depth-1)
+	return p, depth
 }
 
-type ptrAndSizeGetter interface {
-	getPtrAndSize(p unsafe.Pointer) (unsafe.Pointer, uintptr)
+// deref resolves the field at offset through ptrDepth pointers. It returns nil
+// if one of them is nil, in which case the nil is already written to w.
+func deref(w *writer, p unsafe.Pointer, offset uintptr, ptrDepth int) unsafe.Pointer {
+	np, level := indirect(unsafe.Pointer(uintptr(p)+offset), ptrDepth)
+	if ptrDepth == 0 {
+		return np
+	}
+	if np == nil {
+		w.writeNil(level)
+		return nil
+	}
+
+	w.writeFrame(uint64(level))
+	return np
 }
 
 type baseTypeGetter struct {
@@ -26,9 +41,22 @@ type baseTypeGetter struct {
 	elemSz   uintptr
 }
 
-func (b *baseTypeGetter) getPtrAndSize(p unsafe.Pointer) (unsafe.Pointer, uintptr) {
-	np := indirect(unsafe.Pointer(uintptr(p)+b.offset), b.ptrDepth)
-	return np, b.elemSz
+func (b *baseTypeGetter) writeTo(w *writer, p unsafe.Pointer) {
+	np := deref(w, p, b.offset, b.ptrDepth)
+	if np == nil {
+		return
+	}
+
+	w.write(np, b.elemSz)
+}
+
+func (b *baseTypeGetter) equal(e *equaler, pa, pb unsafe.Pointer) bool {
+	na, nb, equal, done := derefEqual(pa, pb, b.offset, b.ptrDepth)
+	if done {
+		return equal
+	}
+
+	return bytes.Equal(unsafe.Slice((*byte)(na), b.elemSz), unsafe.Slice((*byte)(nb), b.elemSz))
 }
 
 type stringGetter struct {
@@ -36,32 +64,421 @@ type stringGetter struct {
 	ptrDepth int
 }
 
-func (s *stringGetter) getPtrAndSize(p unsafe.Pointer) (unsafe.Pointer, uintptr) {
-	np := indirect(unsafe.Pointer(uintptr(p)+s.offset), s.ptrDepth)
+func (s *stringGetter) writeTo(w *writer, p unsafe.Pointer) {
+	np := deref(w, p, s.offset, s.ptrDepth)
+	if np == nil {
+		return
+	}
+
 	sh := (*reflect.StringHeader)(np)
-	return unsafe.Pointer(sh.Data), uintptr(sh.Len)
+	w.writeFrame(uint64(sh.Len) + 1)
+	w.write(unsafe.Pointer(sh.Data), uintptr(sh.Len))
+}
+
+func (s *stringGetter) equal(e *equaler, a, b unsafe.Pointer) bool {
+	na, nb, equal, done := derefEqual(a, b, s.offset, s.ptrDepth)
+	if done {
+		return equal
+	}
+
+	return *(*string)(na) == *(*string)(nb)
 }
 
 type sliceGetter struct {
+	offset    uintptr
+	ptrDepth  int
+	elemSz    int
+	floats    []floatPos
+	spans     []span
+	scalars   []scalar
+	rawFloats bool
+}
+
+func (s *sliceGetter) writeTo(w *writer, p unsafe.Pointer) {
+	np := deref(w, p, s.offset, s.ptrDepth)
+	if np == nil {
+		return
+	}
+
+	sh := (*reflect.SliceHeader)(np)
+	if sh.Data == 0 {
+		w.writeFrame(frameNil)
+	} else {
+		w.writeFrame(uint64(sh.Len) + 1)
+	}
+	data, sz := unsafe.Pointer(sh.Data), uintptr(sh.Len*s.elemSz)
+	if s.scalars != nil {
+		w.writeBytes(encodeBlock(data, sh.Len, uintptr(s.elemSz), s.scalars, s.rawFloats))
+		return
+	}
+	if len(s.floats) != 0 {
+		data = canonicalBlock(data, sh.Len, uintptr(s.elemSz), s.floats)
+	}
+	if s.spans != nil {
+		data, sz = compactBlock(data, sh.Len, uintptr(s.elemSz), s.spans)
+	}
+	w.write(data, sz)
+}
+
+func (s *sliceGetter) equal(e *equaler, a, b unsafe.Pointer) bool {
+	na, nb, equal, done := derefEqual(a, b, s.offset, s.ptrDepth)
+	if done {
+		return equal
+	}
+
+	sa, sb := (*reflect.SliceHeader)(na), (*reflect.SliceHeader)(nb)
+	if !e.sliceFramesEqual(sa, sb) {
+		return false
+	}
+	return blockEqual(unsafe.Pointer(sa.Data), unsafe.Pointer(sb.Data), sa.Len, uintptr(s.elemSz), s.floats, s.spans)
+}
+
+type arrayGetter struct {
+	offset    uintptr
+	ptrDepth  int
+	len       int
+	elemSz    uintptr
+	floats    []floatPos
+	spans     []span
+	scalars   []scalar
+	rawFloats bool
+}
+
+func (a *arrayGetter) writeTo(w *writer, p unsafe.Pointer) {
+	np := deref(w, p, a.offset, a.ptrDepth)
+	if np == nil {
+		return
+	}
+
+	if a.scalars != nil {
+		w.writeBytes(encodeBlock(np, a.len, a.elemSz, a.scalars, a.rawFloats))
+		return
+	}
+	sz := uintptr(a.len) * a.elemSz
+	if len(a.floats) != 0 {
+		np = canonicalBlock(np, a.len, a.elemSz, a.floats)
+	}
+	if a.spans != nil {
+		np, sz = compactBlock(np, a.len, a.elemSz, a.spans)
+	}
+	w.write(np, sz)
+}
+
+func (a *arrayGetter) equal(e *equaler, pa, pb unsafe.Pointer) bool {
+	na, nb, equal, done := derefEqual(pa, pb, a.offset, a.ptrDepth)
+	if done {
+		return equal
+	}
+
+	return blockEqual(na, nb, a.len, a.elemSz, a.floats, a.spans)
+}
+
+type structPtrGetter struct {
 	offset   uintptr
 	ptrDepth int
-	elemSz   int
+	elem     *plan
+}
+
+func (s *structPtrGetter) writeTo(w *writer, p unsafe.Pointer) {
+	np := deref(w, p, s.offset, s.ptrDepth)
+	if np == nil || !w.enter(np, s.elem) {
+		return
+	}
+
+	s.elem.writeTo(w, np)
+	w.leave(np, s.elem)
 }
 
-func (s *sliceGetter) getPtrAndSize(p unsafe.Pointer) (unsafe.Pointer, uintptr) {
-	np := indirect(unsafe.Pointer(uintptr(p)+s.offset), s.ptrDepth)
+func (s *structPtrGetter) equal(e *equaler, a, b unsafe.Pointer) bool {
+	na, nb, equal, done := derefEqual(a, b, s.offset, s.ptrDepth)
+	if done {
+		return equal
+	}
+	if equal, done := e.enter(na, nb, s.elem, 0); done {
+		return equal
+	}
+
+	equal = s.elem.equal(e, na, nb)
+	e.leave()
+	return equal
+}
+
+type sliceElemsGetter struct {
+	offset   uintptr
+	ptrDepth int
+	elemSz   uintptr
+	elem     *plan
+
+	// cyclic slices may be reached again from their elements.
+	cyclic bool
+}
+
+func (s *sliceElemsGetter) writeTo(w *writer, p unsafe.Pointer) {
+	np := deref(w, p, s.offset, s.ptrDepth)
+	if np == nil {
+		return
+	}
+
 	sh := (*reflect.SliceHeader)(np)
-	return unsafe.Pointer(sh.Data), uintptr(sh.Len * s.elemSz)
+	if sh.Data == 0 {
+		w.writeFrame(frameNil)
+	} else {
+		w.writeFrame(uint64(sh.Len) + 1)
+	}
+	if s.cyclic && sh.Len > 0 {
+		v := visit{unsafe.Pointer(sh.Data), s.elem, sh.Len}
+		if !w.enterContainer(v) {
+			return
+		}
+		defer w.leaveContainer(v)
+	}
+	for i := 0; i < sh.Len; i++ {
+		s.elem.writeTo(w, unsafe.Add(unsafe.Pointer(sh.Data), uintptr(i)*s.elemSz))
+	}
 }
 
-type arrayGetter struct {
+func (s *sliceElemsGetter) equal(e *equaler, a, b unsafe.Pointer) bool {
+	na, nb, equal, done := derefEqual(a, b, s.offset, s.ptrDepth)
+	if done {
+		return equal
+	}
+
+	sa, sb := (*reflect.SliceHeader)(na), (*reflect.SliceHeader)(nb)
+	if !e.sliceFramesEqual(sa, sb) {
+		return false
+	}
+	if s.cyclic && sa.Len > 0 {
+		if equal, done := e.enter(unsafe.Pointer(sa.Data), unsafe.Pointer(sb.Data), s.elem, sa.Len); done {
+			return equal
+		}
+		defer e.leave()
+	}
+	for i := 0; i < sa.Len; i++ {
+		off := uintptr(i) * s.elemSz
+		if !s.elem.equal(e, unsafe.Add(unsafe.Pointer(sa.Data), off), unsafe.Add(unsafe.Pointer(sb.Data), off)) {
+			return false
+		}
+	}
+	return true
+}
+
+type arrayElemsGetter struct {
 	offset   uintptr
 	ptrDepth int
 	len      int
 	elemSz   uintptr
+	elem     *plan
+}
+
+func (a *arrayElemsGetter) writeTo(w *writer, p unsafe.Pointer) {
+	np := deref(w, p, a.offset, a.ptrDepth)
+	if np == nil {
+		return
+	}
+
+	for i := 0; i < a.len; i++ {
+		a.elem.writeTo(w, unsafe.Add(np, uintptr(i)*a.elemSz))
+	}
+}
+
+func (a *arrayElemsGetter) equal(e *equaler, pa, pb unsafe.Pointer) bool {
+	na, nb, equal, done := derefEqual(pa, pb, a.offset, a.ptrDepth)
+	if done {
+		return equal
+	}
+
+	for i := 0; i < a.len; i++ {
+		off := uintptr(i) * a.elemSz
+		if !a.elem.equal(e, unsafe.Add(na, off), unsafe.Add(nb, off)) {
+			return false
+		}
+	}
+	return true
 }
 
-func (a *arrayGetter) getPtrAndSize(p unsafe.Pointer) (unsafe.Pointer, uintptr) {
-	np := indirect(unsafe.Pointer(uintptr(p)+a.offset), a.ptrDepth)
-	return np, uintptr(a.len) * a.elemSz
+// mapGetter hashes every entry with the seed the map starts at and sums the
+// results, so the hash does not depend on the iteration order.
+type mapGetter struct {
+	offset   uintptr
+	ptrDepth int
+	typ      reflect.Type
+	key      *plan
+	elem     *plan
+	plainKey bool
+
+	// cyclic maps may be reached again from their entries.
+	cyclic bool
+}
+
+func (m *mapGetter) writeTo(w *writer, p unsafe.Pointer) {
+	np := deref(w, p, m.offset, m.ptrDepth)
+	if np == nil {
+		return
+	}
+
+	mv := reflect.NewAt(m.typ, np).Elem()
+	if mv.IsNil() {
+		w.writeFrame(frameNil)
+	} else {
+		w.writeFrame(uint64(mv.Len()) + 1)
+	}
+	if m.cyclic && mv.Len() > 0 {
+		v := visit{p: mv.UnsafePointer()}
+		if !w.enterContainer(v) {
+			return
+		}
+		defer w.leaveContainer(v)
+	}
+
+	k := reflect.New(m.typ.Key()).Elem()
+	v := reflect.New(m.typ.Elem()).Elem()
+	var sum [2]uint64
+	for iter := mv.MapRange(); iter.Next(); {
+		k.SetIterKey(iter)
+		v.SetIterValue(iter)
+
+		ew := w.sub()
+		m.key.writeTo(&ew, k.Addr().UnsafePointer())
+		m.elem.writeTo(&ew, v.Addr().UnsafePointer())
+		w.join(&ew)
+		ew.addTo(&sum)
+	}
+	w.writeSum(sum)
+}
+
+// equal pairs the entries of both maps. Keys compared by == as well as by the
+// plan are looked up directly, others are searched for.
+func (m *mapGetter) equal(e *equaler, a, b unsafe.Pointer) bool {
+	na, nb, equal, done := derefEqual(a, b, m.offset, m.ptrDepth)
+	if done {
+		return equal
+	}
+
+	ma, mb := reflect.NewAt(m.typ, na).Elem(), reflect.NewAt(m.typ, nb).Elem()
+	if ma.Len() != mb.Len() || e.framed && ma.IsNil() != mb.IsNil() {
+		return false
+	}
+	if m.cyclic && ma.Len() > 0 {
+		if equal, done := e.enter(ma.UnsafePointer(), mb.UnsafePointer(), nil, 0); done {
+			return equal
+		}
+		defer e.leave()
+	}
+
+	if m.plainKey {
+		k := reflect.New(m.typ.Key()).Elem()
+		va := reflect.New(m.typ.Elem()).Elem()
+		vb := reflect.New(m.typ.Elem()).Elem()
+		for iter := ma.MapRange(); iter.Next(); {
+			k.SetIterKey(iter)
+			v := mb.MapIndex(k)
+			if !v.IsValid() {
+				return false
+			}
+			va.SetIterValue(iter)
+			vb.Set(v)
+			if !m.elem.equal(e, va.Addr().UnsafePointer(), vb.Addr().UnsafePointer()) {
+				return false
+			}
+		}
+		return true
+	}
+
+	ka, va := mapEntries(ma)
+	kb, vb := mapEntries(mb)
+	return matchEqual(len(ka), func(i, j int) bool {
+		return m.key.equal(e, ka[i], kb[j]) && m.elem.equal(e, va[i], vb[j])
+	})
+}
+
+// mapEntries returns pointers to copies of the keys and values of mv.
+func mapEntries(mv reflect.Value) (keys, values []unsafe.Pointer) {
+	for iter := mv.MapRange(); iter.Next(); {
+		keys = append(keys, copyValue(iter.Key()).Addr().UnsafePointer())
+		values = append(values, copyValue(iter.Value()).Addr().UnsafePointer())
+	}
+	return keys, values
+}
+
+// copyValue returns an addressable copy of v.
+func copyValue(v reflect.Value) reflect.Value {
+	c := reflect.New(v.Type()).Elem()
+	c.Set(v)
+	return c
+}
+
+// plainKey reports whether keys of typ that are equal by the plan are also
+// equal by ==, so they can be looked up in a map.
+func plainKey(typ reflect.Type) bool {
+	if hasHashHook(typ) {
+		return false
+	}
+	switch typ.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	case reflect.Array:
+		return plainKey(typ.Elem())
+	case reflect.Struct:
+		if hasTags(typ) {
+			return false
+		}
+		for i := 0; i < typ.NumField(); i++ {
+			if !plainKey(typ.Field(i).Type) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// interfaceGetter hashes the identity of the dynamic type followed by the
+// value, using a plan built for the dynamic type on first use.
+type interfaceGetter struct {
+	offset   uintptr
+	ptrDepth int
+	typ      reflect.Type
+	dyn      *dynPlans
+}
+
+func (i *interfaceGetter) writeTo(w *writer, p unsafe.Pointer) {
+	np := deref(w, p, i.offset, i.ptrDepth)
+	if np == nil {
+		return
+	}
+
+	iv := reflect.NewAt(i.typ, np).Elem()
+	if iv.IsNil() {
+		w.writeMark(frameNil, nilIfaceSeed)
+		return
+	}
+
+	dp := i.dyn.planOf(iv.Elem().Type())
+	w.writeFrame(uint64(len(dp.ident)) + 1)
+	w.writeString(dp.ident)
+
+	v := reflect.New(dp.typ).Elem()
+	v.Set(iv.Elem())
+	dp.pl.writeTo(w, v.Addr().UnsafePointer())
+}
+
+func (i *interfaceGetter) equal(e *equaler, a, b unsafe.Pointer) bool {
+	na, nb, equal, done := derefEqual(a, b, i.offset, i.ptrDepth)
+	if done {
+		return equal
+	}
+
+	ia, ib := reflect.NewAt(i.typ, na).Elem(), reflect.NewAt(i.typ, nb).Elem()
+	if ia.IsNil() || ib.IsNil() {
+		return ia.IsNil() && ib.IsNil()
+	}
+	if ia.Elem().Type() != ib.Elem().Type() {
+		return false
+	}
+
+	dp := i.dyn.planOf(ia.Elem().Type())
+	va, vb := copyValue(ia.Elem()), copyValue(ib.Elem())
+	return dp.pl.equal(e, va.Addr().UnsafePointer(), vb.Addr().UnsafePointer())
 }
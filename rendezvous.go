@@ -0,0 +1,107 @@
+package anyhash
+
+import (
+	"math"
+	"sort"
+)
+
+// Weighted is a node with a weight for Rendezvous.
+type Weighted[N any] struct {
+	Node   N
+	Weight float64
+}
+
+// Rendezvous selects nodes for keys by highest random weight: every node is
+// scored by the hash of the node and the key, scaled by its weight, and a key
+// belongs to the node with the highest score. Nodes get keys in proportion to
+// their weights, and adding or removing a node moves only the keys that it
+// takes or gives up. Unlike Ring it keeps no state but the nodes, and
+// selection takes time linear in their number.
+type Rendezvous[N, K any] struct {
+	keyHasher *AnyHasher[K]
+	nodes     []Weighted[N]
+	hashes    []uint64
+}
+
+// NewRendezvous returns a selector of nodes, hashed with nodes, for keys
+// hashed with keys. Weights must be positive. Equal nodes must not be given
+// twice.
+func NewRendezvous[N, K any](nodes *AnyHasher[N], keys *AnyHasher[K], ns []Weighted[N]) *Rendezvous[N, K] {
+	r := &Rendezvous[N, K]{
+		keyHasher: keys,
+		nodes:     append([]Weighted[N](nil), ns...),
+		hashes:    make([]uint64, len(ns)),
+	}
+	for i, n := range ns {
+		if !(n.Weight > 0) || math.IsInf(n.Weight, 1) {
+			panic("anyhash: rendezvous node weight must be positive and finite")
+		}
+		r.hashes[i] = nodes.GetHash64(n.Node)
+	}
+	return r
+}
+
+// Get returns the node that k belongs to, or false if there are no nodes.
+func (r *Rendezvous[N, K]) Get(k K) (N, bool) {
+	if len(r.nodes) == 0 {
+		var zero N
+		return zero, false
+	}
+
+	hash := r.keyHasher.GetHash64(k)
+	best, bestScore := 0, r.score(0, hash)
+	for i := 1; i < len(r.nodes); i++ {
+		if s := r.score(i, hash); r.better(i, s, best, bestScore) {
+			best, bestScore = i, s
+		}
+	}
+	return r.nodes[best].Node, true
+}
+
+// GetN returns up to n distinct nodes for k in the order of their scores: the
+// node that k belongs to, followed by the ones it would belong to if the
+// nodes before were removed.
+func (r *Rendezvous[N, K]) GetN(k K, n int) []N {
+	if n > len(r.nodes) {
+		n = len(r.nodes)
+	}
+	if n <= 0 {
+		return nil
+	}
+
+	hash := r.keyHasher.GetHash64(k)
+	order := make([]int, len(r.nodes))
+	scores := make([]float64, len(r.nodes))
+	for i := range order {
+		order[i], scores[i] = i, r.score(i, hash)
+	}
+	sort.Slice(order, func(i, j int) bool {
+		a, b := order[i], order[j]
+		return r.better(a, scores[a], b, scores[b])
+	})
+
+	nodes := make([]N, n)
+	for i := range nodes {
+		nodes[i] = r.nodes[order[i]].Node
+	}
+	return nodes
+}
+
+// score returns the score of the i-th node for the key hash: its weight over
+// -ln u, where u is uniform in (0, 1), so that the highest score falls to a
+// node with probability proportional to its weight. The hashes are mixed into
+// u, so the native 32-bit hashes of 32-bit platforms do as well.
+func (r *Rendezvous[N, K]) score(i int, hash uint64) float64 {
+	u := (float64(combineHashes(r.hashes[i], hash)>>11) + 0.5) / (1 << 53)
+	return r.nodes[i].Weight / -math.Log(u)
+}
+
+// better reports whether node i with score si beats node j with score sj.
+// Ties are broken by node hashes, so that they do not depend on the order of
+// the nodes.
+func (r *Rendezvous[N, K]) better(i int, si float64, j int, sj float64) bool {
+	if si != sj {
+		return si > sj
+	}
+	return r.hashes[i] > r.hashes[j]
+}
@@ -0,0 +1,150 @@
+package anyhash
+
+import (
+	"math"
+	"testing"
+)
+
+func testWeighted(nodes []testRingNode, weight func(i int) float64) []Weighted[testRingNode] {
+	ws := make([]Weighted[testRingNode], len(nodes))
+	for i, n := range nodes {
+		ws[i] = Weighted[testRingNode]{Node: n, Weight: weight(i)}
+	}
+	return ws
+}
+
+func TestRendezvous(t *testing.T) {
+	n := 100000
+	if testing.Short() {
+		n = 20000
+	}
+	keys := testKeys(0, n)
+	one := func(int) float64 { return 1 }
+	nodeHasher, keyHasher := newTestHasher[testRingNode](t, WithPortable()), newTestHasher[testKey](t, WithPortable())
+
+	t.Run("Empty", func(t *testing.T) {
+		r := NewRendezvous(nodeHasher, keyHasher, nil)
+		if _, ok := r.Get(keys[0]); ok {
+			t.Fatal("got node without nodes")
+		}
+		if got := r.GetN(keys[0], 2); len(got) != 0 {
+			t.Fatalf("got %d nodes, want 0", len(got))
+		}
+	})
+
+	t.Run("Distribution", func(t *testing.T) {
+		nodes := testRingNodes(10)
+		for name, opts := range testHasherOptions {
+			r := NewRendezvous(newTestHasher[testRingNode](t, opts...), newTestHasher[testKey](t, opts...), testWeighted(nodes, one))
+
+			loads := map[testRingNode]int{}
+			for _, k := range keys {
+				node, _ := r.Get(k)
+				loads[node]++
+			}
+			mean := float64(n) / float64(len(nodes))
+			for _, node := range nodes {
+				if d := math.Abs(float64(loads[node])-mean) / mean; d > 0.1 {
+					t.Fatalf("%s: %v: got %d keys, want %.0f±10%%", name, node, loads[node], mean)
+				}
+			}
+		}
+	})
+
+	t.Run("Weights", func(t *testing.T) {
+		nodes := testRingNodes(4)
+		r := NewRendezvous(nodeHasher, keyHasher, testWeighted(nodes, func(i int) float64 { return float64(i + 1) }))
+
+		loads := map[testRingNode]int{}
+		for _, k := range keys {
+			node, _ := r.Get(k)
+			loads[node]++
+		}
+		for i, node := range nodes {
+			want := float64(n) * float64(i+1) / 10
+			if d := math.Abs(float64(loads[node])-want) / want; d > 0.1 {
+				t.Fatalf("%v of weight %d: got %d keys, want %.0f±10%%", node, i+1, loads[node], want)
+			}
+		}
+	})
+
+	t.Run("Disruption", func(t *testing.T) {
+		nodes := testRingNodes(11)
+		r := NewRendezvous(nodeHasher, keyHasher, testWeighted(nodes[:10], one))
+		before := make([]testRingNode, len(keys))
+		for i, k := range keys {
+			before[i], _ = r.Get(k)
+		}
+
+		// Only the keys taken by an added node move.
+		added := NewRendezvous(nodeHasher, keyHasher, testWeighted(nodes, one))
+		moved := 0
+		for i, k := range keys {
+			got, _ := added.Get(k)
+			if got == before[i] {
+				continue
+			}
+			if got != nodes[10] {
+				t.Fatalf("%v moved from %v to %v, not to the new node", k, before[i], got)
+			}
+			moved++
+		}
+		if want := float64(n) / 11; math.Abs(float64(moved)-want)/want > 0.1 {
+			t.Fatalf("got %d moved keys, want %.0f±10%%", moved, want)
+		}
+
+		// Only the keys of a removed node move, whatever the order of the rest.
+		rest := append([]testRingNode{}, nodes[4:10]...)
+		rest = append(rest, nodes[:3]...)
+		removed := NewRendezvous(nodeHasher, keyHasher, testWeighted(rest, one))
+		for i, k := range keys {
+			got, _ := removed.Get(k)
+			if before[i] != nodes[3] && got != before[i] {
+				t.Fatalf("%v moved from %v to %v", k, before[i], got)
+			}
+			if got == nodes[3] {
+				t.Fatalf("%v belongs to removed node", k)
+			}
+		}
+	})
+
+	t.Run("GetN", func(t *testing.T) {
+		nodes := testRingNodes(5)
+		r := NewRendezvous(nodeHasher, keyHasher, testWeighted(nodes, one))
+		for _, k := range keys[:1000] {
+			replicas := r.GetN(k, 3)
+			if len(replicas) != 3 {
+				t.Fatalf("got %d replicas, want 3", len(replicas))
+			}
+			if first, _ := r.Get(k); replicas[0] != first {
+				t.Fatalf("%v: got first replica %v, want %v", k, replicas[0], first)
+			}
+			if replicas[0] == replicas[1] || replicas[1] == replicas[2] || replicas[0] == replicas[2] {
+				t.Fatalf("%v: got replicas %v, want distinct", k, replicas)
+			}
+
+			// The second replica is the node a key falls back to.
+			var rest []testRingNode
+			for _, node := range nodes {
+				if node != replicas[0] {
+					rest = append(rest, node)
+				}
+			}
+			if got, _ := NewRendezvous(nodeHasher, keyHasher, testWeighted(rest, one)).Get(k); got != replicas[1] {
+				t.Fatalf("%v: got %v, want %v", k, got, replicas[1])
+			}
+		}
+		if got := r.GetN(keys[0], 10); len(got) != len(nodes) {
+			t.Fatalf("got %d replicas, want %d", len(got), len(nodes))
+		}
+	})
+}
+
+func BenchmarkRendezvous(b *testing.B) {
+	r := NewRendezvous(newTestHasher[testRingNode](b), newTestHasher[testKey](b), testWeighted(testRingNodes(20), func(int) float64 { return 1 }))
+	ks := testKeys(0, 1024)
+
+	for i := 0; i < b.N; i++ {
+		r.Get(ks[i%len(ks)])
+	}
+}
@@ -0,0 +1,212 @@
+package anyhash
+
+import (
+	"encoding/binary"
+	"sort"
+	"unsafe"
+
+	"github.com/hikitani/anyhash/internal"
+)
+
+// Ring is a consistent hash ring that maps keys of any type hashable by
+// AnyHasher to nodes of any such type. Each node is placed on the ring as
+// virtual nodes in proportion to its weight, and a key belongs to the node of
+// the first virtual node following the hash of the key. Adding or removing a
+// node moves only the keys that it takes or gives up.
+//
+// Placement depends only on the hashes of nodes and keys, so rings of
+// portable hashers with the same nodes agree on every platform. A Ring is not
+// safe for concurrent use.
+type Ring[N, K any] struct {
+	nodeHasher *AnyHasher[N]
+	keyHasher  *AnyHasher[K]
+	vnodes     int
+	nodes      []*ringNode[N]
+	points     []ringPoint[N]
+}
+
+type ringNode[N any] struct {
+	hash   uint64
+	node   N
+	weight int
+}
+
+type ringPoint[N any] struct {
+	hash uint64
+	node *ringNode[N]
+	i    int
+}
+
+// NewRing returns an empty ring hashing nodes with nodes and keys with keys,
+// that places vnodes virtual nodes per unit of weight. A hundred or more
+// virtual nodes balance the load within a few percent.
+func NewRing[N, K any](nodes *AnyHasher[N], keys *AnyHasher[K], vnodes int) *Ring[N, K] {
+	if vnodes < 1 {
+		panic("anyhash: ring must have at least one virtual node per weight")
+	}
+	return &Ring[N, K]{nodeHasher: nodes, keyHasher: keys, vnodes: vnodes}
+}
+
+// Len returns the number of nodes in the ring.
+func (r *Ring[N, K]) Len() int {
+	return len(r.nodes)
+}
+
+// Add adds node n with weight to the ring, or changes the weight of n if it
+// is already there. The virtual nodes of n do not depend on its weight, so
+// raising the weight only adds virtual nodes and lowering it only removes
+// them.
+func (r *Ring[N, K]) Add(n N, weight int) {
+	if weight < 1 {
+		panic("anyhash: ring node weight must be positive")
+	}
+
+	hash := r.nodeHasher.GetHash64(n)
+	node := r.find(hash, n)
+	if node == nil {
+		node = &ringNode[N]{hash: hash, node: n}
+		r.nodes = append(r.nodes, node)
+	}
+
+	old, count := node.weight*r.vnodes, weight*r.vnodes
+	node.weight = weight
+	if count < old {
+		r.removePoints(func(p ringPoint[N]) bool {
+			return p.node == node && p.i >= count
+		})
+		return
+	}
+	for i := old; i < count; i++ {
+		r.points = append(r.points, ringPoint[N]{hash: combineHashes(hash, uint64(i)), node: node, i: i})
+	}
+	sort.Slice(r.points, func(i, j int) bool {
+		return r.points[i].less(r.points[j])
+	})
+}
+
+// Remove removes node n from the ring and reports whether it was there.
+func (r *Ring[N, K]) Remove(n N) bool {
+	node := r.find(r.nodeHasher.GetHash64(n), n)
+	if node == nil {
+		return false
+	}
+
+	for i, other := range r.nodes {
+		if other == node {
+			r.nodes = append(r.nodes[:i], r.nodes[i+1:]...)
+			break
+		}
+	}
+	r.removePoints(func(p ringPoint[N]) bool {
+		return p.node == node
+	})
+	return true
+}
+
+// Get returns the node that k belongs to, or false if the ring is empty.
+func (r *Ring[N, K]) Get(k K) (N, bool) {
+	if len(r.points) == 0 {
+		var zero N
+		return zero, false
+	}
+	return r.points[r.search(r.keyHash(k))].node.node, true
+}
+
+// GetN returns up to n distinct nodes for k, such as the replicas of k: the
+// node that k belongs to, followed by the ones it would belong to if the
+// nodes before were removed.
+func (r *Ring[N, K]) GetN(k K, n int) []N {
+	if n > len(r.nodes) {
+		n = len(r.nodes)
+	}
+	if n <= 0 {
+		return nil
+	}
+
+	nodes := make([]N, 0, n)
+	seen := make([]*ringNode[N], 0, n)
+	i := r.search(r.keyHash(k))
+	for len(nodes) < n {
+		node := r.points[i].node
+		if !containsNode(seen, node) {
+			seen = append(seen, node)
+			nodes = append(nodes, node.node)
+		}
+		if i++; i == len(r.points) {
+			i = 0
+		}
+	}
+	return nodes
+}
+
+func (r *Ring[N, K]) find(hash uint64, n N) *ringNode[N] {
+	for _, node := range r.nodes {
+		if node.hash == hash && r.nodeHasher.Equal(node.node, n) {
+			return node
+		}
+	}
+	return nil
+}
+
+// keyHash returns the hash of k on the ring. Native hashes of 32-bit
+// platforms would all fall before the first virtual node, so they are mixed
+// to 64 bits.
+func (r *Ring[N, K]) keyHash(k K) uint64 {
+	hash := r.keyHasher.GetHash64(k)
+	if !r.keyHasher.hashes64() {
+		hash = combineHashes(ringKeySeed, hash)
+	}
+	return hash
+}
+
+const ringKeySeed = 0x7269_6e67_5f6b_6579
+
+// search returns the index of the first point following hash, wrapping
+// around the ring.
+func (r *Ring[N, K]) search(hash uint64) int {
+	i := sort.Search(len(r.points), func(i int) bool {
+		return r.points[i].hash >= hash
+	})
+	if i == len(r.points) {
+		i = 0
+	}
+	return i
+}
+
+func (r *Ring[N, K]) removePoints(remove func(p ringPoint[N]) bool) {
+	points := r.points[:0]
+	for _, p := range r.points {
+		if !remove(p) {
+			points = append(points, p)
+		}
+	}
+	for i := len(points); i < len(r.points); i++ {
+		r.points[i] = ringPoint[N]{}
+	}
+	r.points = points
+}
+
+// less orders points by hash, breaking ties by the hashes of their nodes so
+// that the order does not depend on the order of adding.
+func (p ringPoint[N]) less(q ringPoint[N]) bool {
+	if p.hash != q.hash {
+		return p.hash < q.hash
+	}
+	return p.node.hash < q.node.hash
+}
+
+func containsNode[N any](nodes []*ringNode[N], node *ringNode[N]) bool {
+	for _, n := range nodes {
+		if n == node {
+			return true
+		}
+	}
+	return false
+}
+
+// combineHashes hashes b with a as the seed, the same way on every platform.
+func combineHashes(a, b uint64) uint64 {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], b)
+	return internal.Hash64(unsafe.Pointer(&buf), a, 8)
+}
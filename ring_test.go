@@ -0,0 +1,235 @@
+package anyhash
+
+import (
+	"math"
+	"strconv"
+	"testing"
+)
+
+type testRingNode struct {
+	Host string
+	Port int
+}
+
+func testRingNodes(n int) []testRingNode {
+	nodes := make([]testRingNode, n)
+	for i := range nodes {
+		nodes[i] = testRingNode{Host: "10.0.0." + strconv.Itoa(i), Port: 6379}
+	}
+	return nodes
+}
+
+// testRingLoads returns the number of keys of every node.
+func testRingLoads(t *testing.T, r *Ring[testRingNode, testKey], keys []testKey) map[testRingNode]int {
+	loads := map[testRingNode]int{}
+	for _, k := range keys {
+		n, ok := r.Get(k)
+		if !ok {
+			t.Fatalf("no node for %v", k)
+		}
+		loads[n]++
+	}
+	return loads
+}
+
+func TestRing(t *testing.T) {
+	n := 100000
+	if testing.Short() {
+		n = 20000
+	}
+	keys := testKeys(0, n)
+	nodeHasher, keyHasher := newTestHasher[testRingNode](t, WithPortable()), newTestHasher[testKey](t, WithPortable())
+
+	t.Run("Empty", func(t *testing.T) {
+		r := NewRing(nodeHasher, keyHasher, 10)
+		if _, ok := r.Get(keys[0]); ok {
+			t.Fatal("got node from empty ring")
+		}
+		if got := r.GetN(keys[0], 3); len(got) != 0 {
+			t.Fatalf("got %d nodes, want 0", len(got))
+		}
+		if r.Remove(testRingNode{}) {
+			t.Fatal("removed node from empty ring")
+		}
+	})
+
+	t.Run("Balance", func(t *testing.T) {
+		for name, opts := range testHasherOptions {
+			r := NewRing(newTestHasher[testRingNode](t, opts...), newTestHasher[testKey](t, opts...), 160)
+			nodes := testRingNodes(10)
+			for _, node := range nodes {
+				r.Add(node, 1)
+			}
+			if r.Len() != len(nodes) {
+				t.Fatalf("%s: got %d nodes, want %d", name, r.Len(), len(nodes))
+			}
+
+			mean := float64(n) / float64(len(nodes))
+			loads := testRingLoads(t, r, keys)
+			for _, node := range nodes {
+				if d := math.Abs(float64(loads[node])-mean) / mean; d > 0.25 {
+					t.Fatalf("%s: %v: got %d keys, want %.0f±25%%", name, node, loads[node], mean)
+				}
+			}
+		}
+	})
+
+	t.Run("Weights", func(t *testing.T) {
+		r := NewRing(nodeHasher, keyHasher, 100)
+		nodes := testRingNodes(4)
+		for i, node := range nodes {
+			r.Add(node, i+1)
+		}
+
+		loads := testRingLoads(t, r, keys)
+		for i, node := range nodes {
+			want := float64(n) * float64(i+1) / 10
+			if d := math.Abs(float64(loads[node])-want) / want; d > 0.25 {
+				t.Fatalf("%v of weight %d: got %d keys, want %.0f±25%%", node, i+1, loads[node], want)
+			}
+		}
+	})
+
+	t.Run("Movement", func(t *testing.T) {
+		r := NewRing(nodeHasher, keyHasher, 160)
+		nodes := testRingNodes(11)
+		for _, node := range nodes[:10] {
+			r.Add(node, 1)
+		}
+		before := make([]testRingNode, len(keys))
+		for i, k := range keys {
+			before[i], _ = r.Get(k)
+		}
+
+		// Only the keys taken by the new node move.
+		r.Add(nodes[10], 1)
+		moved := 0
+		for i, k := range keys {
+			got, _ := r.Get(k)
+			if got == before[i] {
+				continue
+			}
+			if got != nodes[10] {
+				t.Fatalf("%v moved from %v to %v, not to the new node", k, before[i], got)
+			}
+			moved++
+		}
+		if want := float64(n) / 11; math.Abs(float64(moved)-want)/want > 0.25 {
+			t.Fatalf("got %d moved keys, want %.0f±25%%", moved, want)
+		}
+
+		// Removing it moves them back.
+		if !r.Remove(nodes[10]) {
+			t.Fatal("node to remove not found")
+		}
+		for i, k := range keys {
+			if got, _ := r.Get(k); got != before[i] {
+				t.Fatalf("%v: got %v, want %v", k, got, before[i])
+			}
+		}
+
+		// Only the keys of a removed node move.
+		r.Remove(nodes[3])
+		for i, k := range keys {
+			got, _ := r.Get(k)
+			if before[i] != nodes[3] && got != before[i] {
+				t.Fatalf("%v moved from %v to %v", k, before[i], got)
+			}
+			if got == nodes[3] {
+				t.Fatalf("%v belongs to removed node", k)
+			}
+		}
+	})
+
+	t.Run("Reweight", func(t *testing.T) {
+		r := NewRing(nodeHasher, keyHasher, 50)
+		nodes := testRingNodes(5)
+		for _, node := range nodes {
+			r.Add(node, 2)
+		}
+		before := make([]testRingNode, len(keys))
+		for i, k := range keys {
+			before[i], _ = r.Get(k)
+		}
+
+		r.Add(nodes[0], 4)
+		r.Add(nodes[0], 2)
+		for i, k := range keys {
+			if got, _ := r.Get(k); got != before[i] {
+				t.Fatalf("%v: got %v, want %v", k, got, before[i])
+			}
+		}
+		if r.Len() != len(nodes) {
+			t.Fatalf("got %d nodes, want %d", r.Len(), len(nodes))
+		}
+	})
+
+	t.Run("Order", func(t *testing.T) {
+		a, b := NewRing(nodeHasher, keyHasher, 20), NewRing(nodeHasher, keyHasher, 20)
+		nodes := testRingNodes(6)
+		for i := range nodes {
+			a.Add(nodes[i], 1)
+			b.Add(nodes[len(nodes)-1-i], 1)
+		}
+		for _, k := range keys[:1000] {
+			na, _ := a.Get(k)
+			nb, _ := b.Get(k)
+			if na != nb {
+				t.Fatalf("%v: got %v and %v", k, na, nb)
+			}
+		}
+	})
+
+	t.Run("GetN", func(t *testing.T) {
+		r := NewRing(nodeHasher, keyHasher, 40)
+		nodes := testRingNodes(5)
+		for _, node := range nodes {
+			r.Add(node, 1)
+		}
+
+		for _, k := range keys[:1000] {
+			replicas := r.GetN(k, 3)
+			if len(replicas) != 3 {
+				t.Fatalf("got %d replicas, want 3", len(replicas))
+			}
+			if first, _ := r.Get(k); replicas[0] != first {
+				t.Fatalf("%v: got first replica %v, want %v", k, replicas[0], first)
+			}
+			if replicas[0] == replicas[1] || replicas[1] == replicas[2] || replicas[0] == replicas[2] {
+				t.Fatalf("%v: got replicas %v, want distinct", k, replicas)
+			}
+		}
+
+		// The second replica is the node a key falls back to.
+		k := keys[0]
+		replicas := r.GetN(k, 10)
+		if len(replicas) != len(nodes) {
+			t.Fatalf("got %d replicas, want %d", len(replicas), len(nodes))
+		}
+		r.Remove(replicas[0])
+		if got, _ := r.Get(k); got != replicas[1] {
+			t.Fatalf("got %v, want %v", got, replicas[1])
+		}
+	})
+}
+
+func BenchmarkRing(b *testing.B) {
+	r := NewRing(newTestHasher[testRingNode](b), newTestHasher[testKey](b), 160)
+	for _, node := range testRingNodes(20) {
+		r.Add(node, 1)
+	}
+	ks := testKeys(0, 1024)
+
+	b.Run("Get", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			r.Get(ks[i%len(ks)])
+		}
+	})
+
+	b.Run("GetN", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			r.GetN(ks[i%len(ks)], 3)
+		}
+	})
+}
@@ -0,0 +1,62 @@
+package anyhash
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"sync"
+)
+
+// Seed is a random seed made by MakeSeed, with a secret SipHash key. The zero
+// Seed is invalid, so a hasher cannot be seeded with zero by mistake.
+type Seed struct {
+	s   uint64
+	key [16]byte
+}
+
+// MakeSeed returns a new random seed read from crypto/rand.
+func MakeSeed() Seed {
+	var b [8 + 16]byte
+	for {
+		if _, err := rand.Read(b[:]); err != nil {
+			panic("anyhash: reading random seed: " + err.Error())
+		}
+		if s := binary.LittleEndian.Uint64(b[:8]); s != 0 {
+			seed := Seed{s: s}
+			copy(seed.key[:], b[8:])
+			return seed
+		}
+	}
+}
+
+var (
+	processSeedOnce sync.Once
+	processSeed     Seed
+)
+
+// ProcessSeed returns the random seed made once per process that NewRandom
+// uses.
+func ProcessSeed() Seed {
+	processSeedOnce.Do(func() {
+		processSeed = MakeSeed()
+	})
+	return processSeed
+}
+
+// NewRandom returns a randomized hasher seeded with ProcessSeed. Its hashes
+// are the same within the process but differ from run to run, so they must
+// not be persisted or shared.
+func NewRandom[T any](opts ...Option) (*AnyHasher[T], error) {
+	return NewSeeded[T](ProcessSeed(), opts...)
+}
+
+// NewSeeded returns a randomized hasher seeded with seed, which must come
+// from MakeSeed. Unless opts set another Algorithm, it hashes with SipHash24
+// keyed with the secret key of seed: a random seed alone does not keep the
+// default algorithm from collisions crafted for every seed, SipHash24 does.
+func NewSeeded[T any](seed Seed, opts ...Option) (*AnyHasher[T], error) {
+	if seed.s == 0 {
+		return nil, errors.New("anyhash: zero Seed, use MakeSeed")
+	}
+	return newHasher[T](seed.s, append([]Option{WithAlgorithm(SipHash24(seed.key))}, opts...))
+}
@@ -0,0 +1,125 @@
+package anyhash
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestMakeSeed(t *testing.T) {
+	seen := map[Seed]bool{}
+	for i := 0; i < 100; i++ {
+		s := MakeSeed()
+		if s == (Seed{}) {
+			t.Fatalf("got zero seed")
+		}
+		if seen[s] {
+			t.Fatalf("seed %v made twice", s)
+		}
+		seen[s] = true
+	}
+}
+
+func TestNewSeeded(t *testing.T) {
+	type record struct {
+		id   int
+		name string
+	}
+	v := record{id: 1, name: "seeded"}
+
+	if _, err := NewSeeded[record](Seed{}); err == nil {
+		t.Fatalf("expected err for zero seed, got nil")
+	}
+
+	s := MakeSeed()
+	h1, err := NewSeeded[record](s)
+	if err != nil {
+		t.Fatalf("expected nil err, got %s", err)
+	}
+	h2, err := NewSeeded[record](s)
+	if err != nil {
+		t.Fatalf("expected nil err, got %s", err)
+	}
+	if got, want := h2.GetHash64(v), h1.GetHash64(v); got != want {
+		t.Fatalf("got %#x, want %#x", got, want)
+	}
+
+	h3, err := NewSeeded[record](MakeSeed())
+	if err != nil {
+		t.Fatalf("expected nil err, got %s", err)
+	}
+	if h3.GetHash64(v) == h1.GetHash64(v) {
+		t.Fatalf("hashers with different seeds have the same hash %#x", h1.GetHash64(v))
+	}
+}
+
+func TestNewRandom(t *testing.T) {
+	if ProcessSeed() != ProcessSeed() {
+		t.Fatalf("process seed changed")
+	}
+
+	h1, err := NewRandom[string]()
+	if err != nil {
+		t.Fatalf("expected nil err, got %s", err)
+	}
+	h2, err := NewRandom[string](WithPortable())
+	if err != nil {
+		t.Fatalf("expected nil err, got %s", err)
+	}
+	hs, err := NewSeeded[string](ProcessSeed())
+	if err != nil {
+		t.Fatalf("expected nil err, got %s", err)
+	}
+	if got, want := h1.GetHash64("random"), hs.GetHash64("random"); got != want {
+		t.Fatalf("got %#x, want %#x", got, want)
+	}
+
+	h0, err := New[string](0)
+	if err != nil {
+		t.Fatalf("expected nil err, got %s", err)
+	}
+	if h2.GetHash64("random") == h0.GetHash64("random") {
+		t.Fatalf("random hasher hashes like a zero seeded one")
+	}
+}
+
+// The first 8 bytes of a 16-byte input equal to a multiplier of the default
+// algorithm cancel the rest of it for every seed. Random hashers must not.
+func TestNewSeededFlooding(t *testing.T) {
+	var b [16]byte
+	binary.LittleEndian.PutUint64(b[:], 0xe7037ed1a0b428db)
+
+	s := MakeSeed()
+	h, err := NewSeeded[[16]byte](s)
+	if err != nil {
+		t.Fatalf("expected nil err, got %s", err)
+	}
+	seen := map[uint64]int{}
+	for i := 0; i < 100; i++ {
+		b[8] = byte(i)
+		hv := h.GetHash64(b)
+		if j, ok := seen[hv]; ok {
+			t.Fatalf("inputs %d and %d have the same hash %#x", j, i, hv)
+		}
+		seen[hv] = i
+	}
+
+	h2, err := NewSeeded[[16]byte](MakeSeed())
+	if err != nil {
+		t.Fatalf("expected nil err, got %s", err)
+	}
+	if h2.GetHash64(b) == h.GetHash64(b) {
+		t.Fatalf("hashers with different seeds have the same hash %#x", h.GetHash64(b))
+	}
+
+	hx, err := NewSeeded[[16]byte](s, WithAlgorithm(XXHash64))
+	if err != nil {
+		t.Fatalf("expected nil err, got %s", err)
+	}
+	want, err := newHasher[[16]byte](s.s, []Option{WithAlgorithm(XXHash64)})
+	if err != nil {
+		t.Fatalf("expected nil err, got %s", err)
+	}
+	if got, want := hx.GetHash64(b), want.GetHash64(b); got != want {
+		t.Fatalf("got %#x, want %#x", got, want)
+	}
+}
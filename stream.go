@@ -0,0 +1,81 @@
+package anyhash
+
+import (
+	"encoding/binary"
+
+	"github.com/hikitani/anyhash/internal"
+)
+
+// Stream hashes a stream of raw bytes and values of T. It implements
+// hash.Hash64.
+//
+// Sum64 is the hash of all bytes written, seeded with the seed of the hasher,
+// where Add(v) writes GetHash64(v) as 8 little endian bytes. It is the 64-bit
+// MemhashFallback, the same on every platform, unless the hasher is made
+// WithAlgorithm, which hashes them instead. So a stream of bytes b hashes as
+// New[[]byte](seed, WithPortable()).GetHash64(b), with the algorithm of the
+// hasher if any. Algorithms only hash whole inputs, so their streams keep
+// all the bytes written until Reset. Values follow the options of the hasher,
+// so their streams are the same on every platform only if it is made
+// WithPortable.
+type Stream[T any] struct {
+	h *AnyHasher[T]
+	d internal.Digest64
+
+	// buf holds the bytes written if the hasher has an Algorithm.
+	buf []byte
+}
+
+// NewStream returns an empty stream hashing values with h.
+func (h *AnyHasher[T]) NewStream() *Stream[T] {
+	s := &Stream[T]{h: h}
+	s.Reset()
+	return s
+}
+
+// Write adds b to the stream. It never returns an error.
+func (s *Stream[T]) Write(b []byte) (int, error) {
+	if s.h.opts.alg != nil {
+		s.buf = append(s.buf, b...)
+	} else {
+		s.d.Write(b)
+	}
+	return len(b), nil
+}
+
+// Add adds the hash of v to the stream.
+func (s *Stream[T]) Add(v T) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], s.h.GetHash64(v))
+	s.Write(b[:])
+}
+
+func (s *Stream[T]) Sum64() uint64 {
+	if s.h.opts.alg != nil {
+		return s.h.opts.alg.Hash(s.buf, s.h.seed)
+	}
+	return s.d.Sum64()
+}
+
+// Sum appends Sum64 to b in big endian order, like hash/fnv.
+func (s *Stream[T]) Sum(b []byte) []byte {
+	v := s.Sum64()
+	return append(b,
+		byte(v>>56), byte(v>>48), byte(v>>40), byte(v>>32),
+		byte(v>>24), byte(v>>16), byte(v>>8), byte(v),
+	)
+}
+
+// Reset empties the stream.
+func (s *Stream[T]) Reset() {
+	s.d.Reset(s.h.seed)
+	s.buf = s.buf[:0]
+}
+
+func (s *Stream[T]) Size() int {
+	return 8
+}
+
+func (s *Stream[T]) BlockSize() int {
+	return 48
+}
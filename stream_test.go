@@ -0,0 +1,104 @@
+package anyhash
+
+import (
+	"encoding/binary"
+	"hash"
+	"testing"
+)
+
+var _ hash.Hash64 = (*Stream[int])(nil)
+
+func TestStream(t *testing.T) {
+	type record struct {
+		id   int
+		name string
+	}
+
+	h, err := New[record](3, WithFraming())
+	if err != nil {
+		t.Fatalf("expected nil err, got %s", err)
+	}
+	hb, err := New[[]byte](3, WithPortable())
+	if err != nil {
+		t.Fatalf("expected nil err, got %s", err)
+	}
+
+	t.Run("Bytes", func(t *testing.T) {
+		data := make([]byte, 200)
+		for i := range data {
+			data[i] = byte(i * 13)
+		}
+
+		s := h.NewStream()
+		for n := 0; n <= len(data); n++ {
+			if got, want := s.Sum64(), hb.GetHash64(data[:n]); got != want {
+				t.Fatalf("len %d: got %#x, want %#x", n, got, want)
+			}
+			if n < len(data) {
+				s.Write(data[n : n+1])
+			}
+		}
+	})
+
+	t.Run("Add", func(t *testing.T) {
+		vs := []record{{1, "a"}, {2, "b"}, {3, "c"}, {4, "d"}, {5, "e"}, {6, "f"}, {7, "g"}}
+
+		s := h.NewStream()
+		var data []byte
+		s.Write([]byte("header"))
+		data = append(data, "header"...)
+		for _, v := range vs {
+			s.Add(v)
+			var b [8]byte
+			binary.LittleEndian.PutUint64(b[:], h.GetHash64(v))
+			data = append(data, b[:]...)
+		}
+
+		if got, want := s.Sum64(), hb.GetHash64(data); got != want {
+			t.Fatalf("got %#x, want %#x", got, want)
+		}
+
+		var b [8]byte
+		binary.BigEndian.PutUint64(b[:], s.Sum64())
+		if got, want := string(s.Sum([]byte("x"))), "x"+string(b[:]); got != want {
+			t.Fatalf("got %q, want %q", got, want)
+		}
+
+		s.Reset()
+		if got, want := s.Sum64(), hb.GetHash64(nil); got != want {
+			t.Fatalf("got %#x, want %#x", got, want)
+		}
+	})
+
+	t.Run("Algorithm", func(t *testing.T) {
+		for _, alg := range []Algorithm{XXHash64, SipHash24([16]byte{1}), SHA256} {
+			ha, err := New[record](3, WithAlgorithm(alg))
+			if err != nil {
+				t.Fatalf("expected nil err, got %s", err)
+			}
+			hab, err := New[[]byte](3, WithPortable(), WithAlgorithm(alg))
+			if err != nil {
+				t.Fatalf("expected nil err, got %s", err)
+			}
+			v := record{1, "a"}
+
+			s := ha.NewStream()
+			s.Write([]byte("header"))
+			s.Add(v)
+			var b [8]byte
+			binary.LittleEndian.PutUint64(b[:], ha.GetHash64(v))
+			data := append([]byte("header"), b[:]...)
+			if got, want := s.Sum64(), hab.GetHash64(data); got != want {
+				t.Fatalf("%T: got %#x, want %#x", alg, got, want)
+			}
+			if got := s.Sum64(); got == hb.GetHash64(data) {
+				t.Fatalf("%T: got the hash without the algorithm %#x", alg, got)
+			}
+
+			s.Reset()
+			if got, want := s.Sum64(), hab.GetHash64(nil); got != want {
+				t.Fatalf("%T: got %#x, want %#x", alg, got, want)
+			}
+		}
+	})
+}
@@ -0,0 +1,115 @@
+package anyhash
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+// hasTags reports whether a field of the struct type typ is tagged.
+func hasTags(typ reflect.Type) bool {
+	for i := 0; i < typ.NumField(); i++ {
+		if _, ok := typ.Field(i).Tag.Lookup("anyhash"); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// omitEmptyGetter skips the field when it holds the zero value. Framed, the
+// field is preceded by whether it is present.
+type omitEmptyGetter struct {
+	offset uintptr
+	typ    reflect.Type
+	field  planStep
+}
+
+func (o *omitEmptyGetter) writeTo(w *writer, p unsafe.Pointer) {
+	if reflect.NewAt(o.typ, unsafe.Add(p, o.offset)).Elem().IsZero() {
+		w.writeFrame(0)
+		return
+	}
+
+	w.writeFrame(1)
+	o.field.writeTo(w, p)
+}
+
+func (o *omitEmptyGetter) equal(e *equaler, a, b unsafe.Pointer) bool {
+	za := reflect.NewAt(o.typ, unsafe.Add(a, o.offset)).Elem().IsZero()
+	zb := reflect.NewAt(o.typ, unsafe.Add(b, o.offset)).Elem().IsZero()
+	if za || zb {
+		return za && zb
+	}
+
+	return o.field.equal(e, a, b)
+}
+
+// unorderedGetter hashes every element with the seed the field starts at and
+// sums the results, the same way mapGetter hashes entries.
+type unorderedGetter struct {
+	offset   uintptr
+	ptrDepth int
+	isSlice  bool
+	len      int
+	elemSz   uintptr
+	elem     *plan
+	// cyclic slices may be reached again from their elements.
+	cyclic bool
+}
+
+func (u *unorderedGetter) writeTo(w *writer, p unsafe.Pointer) {
+	np := deref(w, p, u.offset, u.ptrDepth)
+	if np == nil {
+		return
+	}
+
+	data, n := np, u.len
+	if u.isSlice {
+		sh := (*reflect.SliceHeader)(np)
+		if sh.Data == 0 {
+			w.writeFrame(frameNil)
+		} else {
+			w.writeFrame(uint64(sh.Len) + 1)
+		}
+		data, n = unsafe.Pointer(sh.Data), sh.Len
+		if u.cyclic && n > 0 {
+			v := visit{data, u.elem, n}
+			if !w.enterContainer(v) {
+				return
+			}
+			defer w.leaveContainer(v)
+		}
+	}
+
+	var sum [2]uint64
+	for i := 0; i < n; i++ {
+		ew := w.sub()
+		u.elem.writeTo(&ew, unsafe.Add(data, uintptr(i)*u.elemSz))
+		w.join(&ew)
+		ew.addTo(&sum)
+	}
+	w.writeSum(sum)
+}
+
+func (u *unorderedGetter) equal(e *equaler, a, b unsafe.Pointer) bool {
+	na, nb, equal, done := derefEqual(a, b, u.offset, u.ptrDepth)
+	if done {
+		return equal
+	}
+
+	da, db, n := na, nb, u.len
+	if u.isSlice {
+		sa, sb := (*reflect.SliceHeader)(na), (*reflect.SliceHeader)(nb)
+		if !e.sliceFramesEqual(sa, sb) {
+			return false
+		}
+		da, db, n = unsafe.Pointer(sa.Data), unsafe.Pointer(sb.Data), sa.Len
+		if u.cyclic && n > 0 {
+			e.enter(da, db, u.elem, n)
+			defer e.leave()
+		}
+	}
+
+	return matchEqual(n, func(i, j int) bool {
+		return u.elem.equal(e, unsafe.Add(da, uintptr(i)*u.elemSz), unsafe.Add(db, uintptr(j)*u.elemSz))
+	})
+}
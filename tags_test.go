@@ -0,0 +1,141 @@
+package anyhash
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTagSkip(t *testing.T) {
+	type record struct {
+		id      int
+		updated time.Time    `anyhash:"-"`
+		mu      sync.Mutex   `anyhash:"-"`
+		onClose func()       `anyhash:"-"`
+		cache   map[int]bool `anyhash:"-"`
+	}
+	type plain struct {
+		id int
+	}
+
+	h, err := New[*record](0)
+	if err != nil {
+		t.Fatalf("expected nil err, got %s", err)
+	}
+	hp, err := New[plain](0)
+	if err != nil {
+		t.Fatalf("expected nil err, got %s", err)
+	}
+
+	a := record{id: 1, updated: time.Now(), onClose: func() {}}
+	b := record{id: 1, cache: map[int]bool{1: true}}
+	if got, want := h.GetHash(&a), hp.GetHash(plain{id: 1}); got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+	if got, want := h.GetHash(&b), hp.GetHash(plain{id: 1}); got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+
+	type flat struct {
+		a int16
+		b int16 `anyhash:"-"`
+	}
+	t.Run("Slice[Struct]", testType([]flat{{1, 2}, {3, 4}}, []byte{1, 0}, []byte{3, 0}))
+	t.Run("Array[Struct[Func]]", testType([1]struct {
+		a int16
+		f func() `anyhash:"-"`
+	}{{a: 1}}, []byte{1, 0}))
+}
+
+func TestTagOmitEmpty(t *testing.T) {
+	type v1 struct {
+		id int16
+	}
+	type v2 struct {
+		id    int16
+		label string         `anyhash:"omitempty"`
+		attrs map[string]int `anyhash:"omitempty"`
+	}
+
+	t.Run("Zero", testType(v2{id: 1}, []byte{1, 0}))
+	t.Run("NonZero", testType(v2{id: 1, label: "a"}, []byte{1, 0}, []byte("a")))
+
+	h1, err := New[v1](0)
+	if err != nil {
+		t.Fatalf("expected nil err, got %s", err)
+	}
+	h2, err := New[v2](0)
+	if err != nil {
+		t.Fatalf("expected nil err, got %s", err)
+	}
+	if got, want := h2.GetHash(v2{id: 1}), h1.GetHash(v1{id: 1}); got != want {
+		t.Fatalf("got %d, want %d", got, want)
+	}
+
+	type pair struct {
+		a string `anyhash:"omitempty"`
+		b string `anyhash:"omitempty"`
+	}
+	t.Run("Framed", testFramedDiffer(pair{a: "x"}, pair{b: "x"}, pair{}))
+}
+
+func TestTagUnordered(t *testing.T) {
+	type set struct {
+		ids  []int       `anyhash:"unordered"`
+		tags [3]string   `anyhash:"unordered"`
+		refs []*testNode `anyhash:"unordered,omitempty"`
+	}
+
+	for _, opts := range [][]Option{nil, {WithFraming()}} {
+		name := "Unframed"
+		if opts != nil {
+			name = "Framed"
+		}
+		t.Run(name, func(t *testing.T) {
+			t.Run("Equal", testEqual(opts,
+				set{ids: []int{1, 2, 3}, tags: [3]string{"a", "b", "c"}},
+				set{ids: []int{3, 1, 2}, tags: [3]string{"c", "a", "b"}},
+			))
+			t.Run("EqualRefs", testEqual(opts,
+				set{refs: []*testNode{{v: 1}, newCyclicList(2)}},
+				set{refs: []*testNode{newCyclicList(2), {v: 1}}},
+			))
+			t.Run("Differ", testDiffer(opts,
+				set{ids: []int{1, 2, 3}},
+				set{ids: []int{1, 2, 4}},
+				set{ids: []int{1, 2}},
+				set{ids: []int{1, 2, 3}, tags: [3]string{"a"}},
+			))
+		})
+	}
+}
+
+func TestBadTags(t *testing.T) {
+	t.Run("Unknown", testDisallowedType(struct {
+		a int `anyhash:"sorted"`
+	}{}))
+	t.Run("SkipWithOption", testDisallowedType(struct {
+		a int `anyhash:"-,omitempty"`
+	}{}))
+	t.Run("Duplicate", testDisallowedType(struct {
+		a int `anyhash:"omitempty,omitempty"`
+	}{}))
+	t.Run("Empty", testDisallowedType(struct {
+		a int `anyhash:","`
+	}{}))
+	t.Run("UnorderedInt", testDisallowedType(struct {
+		a int `anyhash:"unordered"`
+	}{}))
+	t.Run("Nested", testDisallowedType([]struct {
+		s struct {
+			a int `anyhash:"omitEmpty"`
+		}
+	}{}))
+	t.Run("Pointer", testDisallowedType(&struct {
+		a int `anyhash:"nope"`
+	}{}))
+	t.Run("Func", testDisallowedType(struct {
+		a int
+		f func() `anyhash:"omitempty"`
+	}{}))
+}
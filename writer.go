@@ -0,0 +1,195 @@
+package anyhash
+
+import (
+	"encoding/binary"
+	"reflect"
+	"unsafe"
+
+	"github.com/hikitani/anyhash/internal"
+)
+
+// frameNil is the frame of a nil slice. Non-nil segments are framed with
+// their length plus one.
+const frameNil = 0
+
+// Seeds separating marks from written values when the writer is not framed.
+const (
+	nilSeed      = 0x9e3779b97f4a7c15
+	cycleSeed    = 0xc2b2ae3d27d4eb4f
+	nilIfaceSeed = 0x165667b19e3779f9
+)
+
+// wideSeed separates the second lane of a wide writer hashing with an
+// Algorithm from the first one.
+const wideSeed = 0x8ebc6af09c88c6e3
+
+// visit is a struct on the pointer path, the one at p hashed with pl, or a
+// slice or map whose elements may lead back to it: the n elements at p hashed
+// with pl, or the map at p with a nil pl.
+type visit struct {
+	p  unsafe.Pointer
+	pl *plan
+	n  int
+}
+
+type writer struct {
+	seed     uint64
+	framed   bool
+	portable bool
+
+	// alg is the hash function set WithAlgorithm, nil for the default one.
+	alg Algorithm
+
+	// wide writers hash on a second lane too, seed2.
+	wide  bool
+	seed2 uint64
+
+	// visiting holds the structs on the current pointer path with their depth,
+	// and containers the slices and maps on it.
+	visiting   map[visit]int
+	containers map[visit]int
+}
+
+// sub returns a writer hashing a part of the value on its own. It starts at
+// the current seed and shares the pointer path with w.
+func (w *writer) sub() writer {
+	return writer{
+		seed:       w.seed,
+		framed:     w.framed,
+		portable:   w.portable,
+		alg:        w.alg,
+		wide:       w.wide,
+		seed2:      w.seed2,
+		visiting:   w.visiting,
+		containers: w.containers,
+	}
+}
+
+// join takes back the pointer path from ew, a writer returned by sub.
+func (w *writer) join(ew *writer) {
+	w.visiting, w.containers = ew.visiting, ew.containers
+}
+
+func (w *writer) write(p unsafe.Pointer, sz uintptr) {
+	w.writeSeeded(p, sz, 0)
+}
+
+// writeSeeded writes sz bytes at p with the seeds xored with domain. Without
+// an Algorithm, portable and wide writers use the same 64-bit hash on every
+// platform, the others the native MemhashFallback.
+func (w *writer) writeSeeded(p unsafe.Pointer, sz uintptr, domain uint64) {
+	switch {
+	case w.alg != nil:
+		b := unsafe.Slice((*byte)(p), sz)
+		if w.wide {
+			w.seed2 = w.alg.Hash(b, w.seed2^domain^wideSeed)
+		}
+		w.seed = w.alg.Hash(b, w.seed^domain)
+	case w.wide:
+		w.seed, w.seed2 = internal.Hash128(p, w.seed^domain, w.seed2^domain, sz)
+	case w.portable:
+		w.seed = internal.Hash64(p, w.seed^domain, sz)
+	default:
+		w.seed = uint64(internal.MemhashFallback(p, uintptr(w.seed^domain), sz))
+	}
+}
+
+// addTo adds the hash of w to sum, which is written with writeSum.
+func (w *writer) addTo(sum *[2]uint64) {
+	sum[0] += w.seed
+	sum[1] += w.seed2
+}
+
+func (w *writer) writeSum(sum [2]uint64) {
+	w.writeUint64(sum[0])
+	if w.wide {
+		w.writeUint64(sum[1])
+	}
+}
+
+// writeUint64 writes v as 8 little endian bytes.
+func (w *writer) writeUint64(v uint64) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], v)
+	w.write(noescape(unsafe.Pointer(&b)), 8)
+}
+
+func (w *writer) writeString(s string) {
+	sh := (*reflect.StringHeader)(unsafe.Pointer(&s))
+	w.write(unsafe.Pointer(sh.Data), uintptr(sh.Len))
+}
+
+func (w *writer) writeBytes(b []byte) {
+	sh := (*reflect.SliceHeader)(unsafe.Pointer(&b))
+	w.write(unsafe.Pointer(sh.Data), uintptr(sh.Len))
+}
+
+func (w *writer) writeFrame(frame uint64) {
+	if w.framed {
+		w.writeUint64(frame)
+	}
+}
+
+// writeMark writes v in place of a value that is not hashed itself. Framed, v
+// is written as a frame; otherwise it is hashed with a seed of its own so it
+// does not line up with written values.
+func (w *writer) writeMark(v uint64, seed uint64) {
+	if w.framed {
+		w.writeUint64(v)
+		return
+	}
+
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], v)
+	w.writeSeeded(noescape(unsafe.Pointer(&b)), 8, seed)
+}
+
+// writeNil marks a nil pointer met after level dereferences. Framed, pointers
+// are always preceded by the number of dereferences, so nil gets a frame less
+// than the pointer depth.
+func (w *writer) writeNil(level int) {
+	w.writeMark(uint64(level), nilSeed)
+}
+
+// enter reports whether the struct at p should be hashed with pl. A struct
+// that is already on the pointer path is written as the distance back to it.
+func (w *writer) enter(p unsafe.Pointer, pl *plan) bool {
+	if !enterPath(w, &w.visiting, visit{p: p, pl: pl}) {
+		return false
+	}
+	w.writeFrame(0)
+	return true
+}
+
+func (w *writer) leave(p unsafe.Pointer, pl *plan) {
+	delete(w.visiting, visit{p: p, pl: pl})
+}
+
+// enterContainer reports whether the elements of the slice or map v should be
+// hashed, like enter. Containers are counted apart from structs, so the
+// distances between structs do not change with the containers in between,
+// which cmd/anyhashgen does not track.
+func (w *writer) enterContainer(v visit) bool {
+	return enterPath(w, &w.containers, v)
+}
+
+func (w *writer) leaveContainer(v visit) {
+	delete(w.containers, v)
+}
+
+// enterPath adds v to path unless it is already on it, in which case it
+// writes the distance back to it to w.
+func enterPath(w *writer, path *map[visit]int, v visit) bool {
+	if *path == nil {
+		*path = map[visit]int{}
+	}
+
+	depth := len(*path)
+	if d, ok := (*path)[v]; ok {
+		w.writeMark(uint64(depth-d), cycleSeed)
+		return false
+	}
+
+	(*path)[v] = depth
+	return true
+}